@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unicornultrafoundation/subnet-rayai-node/bandwidth"
+)
+
+// maxConcurrentBandwidthTests bounds the download and upload tests served
+// at once, so peers cannot saturate the node's link
+const maxConcurrentBandwidthTests = 2
+
+// errBandwidthBusy is returned when too many tests are already running
+var errBandwidthBusy = errors.New("too many bandwidth tests in progress, retry later")
+
+// bandwidthPing answers RTT probes from peers
+func (s *Server) bandwidthPing(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"time": time.Now().UnixNano()})
+}
+
+// bandwidthDownload streams a generated payload of the requested size
+func (s *Server) bandwidthDownload(c *gin.Context) {
+	size := int64(bandwidth.DefaultPayloadSize)
+	if value := c.Query("bytes"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 1 || n > bandwidth.MaxPayloadSize {
+			respondError(c, http.StatusBadRequest, codeInvalidRequest,
+				fmt.Errorf("bytes must be between 1 and %d", bandwidth.MaxPayloadSize))
+			return
+		}
+		size = n
+	}
+
+	if !s.acquireBandwidthSlot(c) {
+		return
+	}
+	defer s.releaseBandwidthSlot()
+
+	c.DataFromReader(http.StatusOK, size, "application/octet-stream", bandwidth.NewPayloadReader(size), nil)
+}
+
+// bandwidthUpload consumes an uploaded payload and reports how much was received
+func (s *Server) bandwidthUpload(c *gin.Context) {
+	if !s.acquireBandwidthSlot(c) {
+		return
+	}
+	defer s.releaseBandwidthSlot()
+
+	start := time.Now()
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, bandwidth.MaxPayloadSize)
+	n, err := io.Copy(io.Discard, body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, codeInvalidRequest, fmt.Errorf("failed to read payload: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bytes":       n,
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+}
+
+// getPeerBandwidth handles requests for the last measurements against peers
+func (s *Server) getPeerBandwidth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"results": s.resourceMgr.PeerBandwidth(),
+	})
+}
+
+// acquireBandwidthSlot reserves one of the concurrent test slots, responding
+// with 429 if none is free
+func (s *Server) acquireBandwidthSlot(c *gin.Context) bool {
+	select {
+	case s.bandwidthSlots <- struct{}{}:
+		return true
+	default:
+		respondError(c, http.StatusTooManyRequests, codeBusy, errBandwidthBusy)
+		return false
+	}
+}
+
+// releaseBandwidthSlot frees a slot taken by acquireBandwidthSlot
+func (s *Server) releaseBandwidthSlot() {
+	<-s.bandwidthSlots
+}
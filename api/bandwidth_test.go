@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unicornultrafoundation/subnet-rayai-node/bandwidth"
+)
+
+// newBandwidthTestServer serves the bandwidth handlers on a loopback listener
+func newBandwidthTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	s := &Server{
+		router:         gin.New(),
+		bandwidthSlots: make(chan struct{}, maxConcurrentBandwidthTests),
+	}
+	s.router.GET("/bandwidth/ping", s.bandwidthPing)
+	s.router.GET("/bandwidth/download", s.bandwidthDownload)
+	s.router.POST("/bandwidth/upload", s.bandwidthUpload)
+
+	srv := httptest.NewServer(s.router)
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func TestMeasureLoopback(t *testing.T) {
+	_, srv := newBandwidthTestServer(t)
+	client := &bandwidth.Client{HTTP: srv.Client(), PayloadSize: 1 << 20, Pings: 3}
+
+	result := client.Measure(context.Background(), srv.URL)
+	if result.Error != "" {
+		t.Fatalf("Measure error: %s", result.Error)
+	}
+	if result.Peer != srv.URL || result.MeasuredAt == 0 {
+		t.Errorf("result = %+v, want peer %s and a measurement time", result, srv.URL)
+	}
+	if result.RTTMs <= 0 || result.RTTMinMs <= 0 || result.RTTMinMs > result.RTTMs {
+		t.Errorf("RTT mean %v min %v, want positive with min <= mean", result.RTTMs, result.RTTMinMs)
+	}
+	if result.DownloadMbps <= 0 || result.UploadMbps <= 0 {
+		t.Errorf("throughput down %v up %v, want positive", result.DownloadMbps, result.UploadMbps)
+	}
+}
+
+func TestMeasureUnreachable(t *testing.T) {
+	_, srv := newBandwidthTestServer(t)
+	srv.Close()
+
+	client := &bandwidth.Client{HTTP: &http.Client{Timeout: time.Second}}
+	result := client.Measure(context.Background(), srv.URL)
+	if !strings.Contains(result.Error, "ping failed") || result.RTTMs != 0 || result.DownloadMbps != 0 {
+		t.Errorf("result = %+v, want a ping failure with zero figures", result)
+	}
+}
+
+func TestBandwidthDownloadSize(t *testing.T) {
+	_, srv := newBandwidthTestServer(t)
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantBytes  int64
+	}{
+		{query: "?bytes=12345", wantStatus: http.StatusOK, wantBytes: 12345},
+		{query: "?bytes=1", wantStatus: http.StatusOK, wantBytes: 1},
+		{query: "", wantStatus: http.StatusOK, wantBytes: bandwidth.DefaultPayloadSize},
+		{query: "?bytes=0", wantStatus: http.StatusBadRequest},
+		{query: "?bytes=-5", wantStatus: http.StatusBadRequest},
+		{query: "?bytes=abc", wantStatus: http.StatusBadRequest},
+		{query: "?bytes=" + strconv.Itoa(bandwidth.MaxPayloadSize+1), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		resp, err := srv.Client().Get(srv.URL + "/bandwidth/download" + tt.query)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.query, err)
+		}
+		n, err := io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("GET %s: reading body: %v", tt.query, err)
+		}
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("GET %s status = %d, want %d", tt.query, resp.StatusCode, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus == http.StatusOK && (n != tt.wantBytes || resp.ContentLength != tt.wantBytes) {
+			t.Errorf("GET %s returned %d bytes (Content-Length %d), want %d", tt.query, n, resp.ContentLength, tt.wantBytes)
+		}
+	}
+}
+
+func TestBandwidthUploadTooLarge(t *testing.T) {
+	s, srv := newBandwidthTestServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/bandwidth/upload",
+		bandwidth.NewPayloadReader(bandwidth.MaxPayloadSize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ContentLength = bandwidth.MaxPayloadSize + 1
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "request body too large") {
+		t.Errorf("upload = %d %s, want 400 for a body too large", resp.StatusCode, body)
+	}
+	if len(s.bandwidthSlots) != 0 {
+		t.Errorf("%d bandwidth slots still held after a rejected upload", len(s.bandwidthSlots))
+	}
+}
+
+func TestBandwidthSlotLimit(t *testing.T) {
+	s, srv := newBandwidthTestServer(t)
+	client := &bandwidth.Client{HTTP: srv.Client(), PayloadSize: 64 << 10, Pings: 1}
+
+	// Hold every slot with uploads whose bodies never finish
+	var writers []*io.PipeWriter
+	done := make(chan int, maxConcurrentBandwidthTests)
+	for i := 0; i < maxConcurrentBandwidthTests; i++ {
+		pr, pw := io.Pipe()
+		writers = append(writers, pw)
+		go func() {
+			resp, err := srv.Client().Post(srv.URL+"/bandwidth/upload", "application/octet-stream", pr)
+			if err != nil {
+				done <- 0
+				return
+			}
+			resp.Body.Close()
+			done <- resp.StatusCode
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.bandwidthSlots) < maxConcurrentBandwidthTests {
+		if time.Now().After(deadline) {
+			t.Fatalf("uploads hold %d slots, want %d", len(s.bandwidthSlots), maxConcurrentBandwidthTests)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	result := client.Measure(context.Background(), srv.URL)
+	if !strings.Contains(result.Error, "429") || result.DownloadMbps != 0 {
+		t.Errorf("Measure with all slots taken = %+v, want a 429 download failure", result)
+	}
+
+	for _, pw := range writers {
+		pw.Write([]byte("payload"))
+		pw.Close()
+	}
+	for i := 0; i < maxConcurrentBandwidthTests; i++ {
+		if status := <-done; status != http.StatusOK {
+			t.Errorf("held upload finished with status %d, want 200", status)
+		}
+	}
+
+	if result := client.Measure(context.Background(), srv.URL); result.Error != "" {
+		t.Errorf("Measure after slots were released: %s", result.Error)
+	}
+}
@@ -1,20 +1,177 @@
 package api
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray"
 )
 
-// getStatus handles requests to get Ray cluster status
+// Error codes returned in the "code" field of error responses
+const (
+	codeInvalidRequest    = "invalid_request"
+	codeAlreadyRunning    = "already_running"
+	codeNotRunning        = "not_running"
+	codeStartFailed       = "start_failed"
+	codeStopFailed        = "stop_failed"
+	codeStatusFailed      = "status_failed"
+	codeStatusParseFailed = "status_parse_failed"
+	codeBusy              = "busy"
+)
+
+// errorResponse is the JSON body returned when a request fails
+type errorResponse struct {
+	Error  string `json:"error"`
+	Code   string `json:"code"`
+	Output string `json:"output,omitempty"` // Output of a failed Ray command
+}
+
+// startHeadRequest is the JSON body accepted by POST /start/head
+type startHeadRequest struct {
+	Port int `json:"port" binding:"omitempty,min=1,max=65535"`
+}
+
+// startWorkerRequest is the JSON body accepted by POST /start/worker
+type startWorkerRequest struct {
+	HeadIP string `json:"headIP" binding:"required,ip|hostname_rfc1123"`
+	Port   int    `json:"port" binding:"omitempty,min=1,max=65535"` // Head node port
+}
+
+// startResponse is the JSON body returned when a Ray node was started
+type startResponse struct {
+	ID   string       `json:"id"`
+	Role ray.NodeRole `json:"role"`
+}
+
+// statusResponse is the JSON body returned by GET /status. Status is null
+// when the raw output was requested but could not be parsed.
+type statusResponse struct {
+	Status *ray.ClusterStatus `json:"status"`
+	Raw    string             `json:"raw,omitempty"`
+}
+
+// respondError writes a structured error response and aborts the request,
+// recording err for the request log
+func respondError(c *gin.Context, status int, code string, err error) {
+	_ = c.Error(err)
+	resp := errorResponse{Error: err.Error(), Code: code}
+	var cmdErr *ray.CommandError
+	if errors.As(err, &cmdErr) {
+		resp.Output = cmdErr.Output
+	}
+	c.AbortWithStatusJSON(status, resp)
+}
+
+// bindJSON binds and validates the request body into req, treating an
+// empty body as a request with all fields unset
+func bindJSON(c *gin.Context, req interface{}) bool {
+	err := c.ShouldBindJSON(req)
+	if errors.Is(err, io.EOF) {
+		err = binding.Validator.ValidateStruct(req)
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, codeInvalidRequest, err)
+		return false
+	}
+	return true
+}
+
+// respondStartError maps a Ray start failure onto an HTTP status
+func respondStartError(c *gin.Context, err error) {
+	if errors.Is(err, ray.ErrAlreadyRunning) {
+		respondError(c, http.StatusConflict, codeAlreadyRunning, err)
+		return
+	}
+	respondError(c, http.StatusInternalServerError, codeStartFailed, err)
+}
+
+// getStatus handles requests to get Ray cluster status. The raw `ray status`
+// output is included when the "raw" query flag is set.
 func (s *Server) getStatus(c *gin.Context) {
-	status, err := s.rayService.GetStatus()
+	output, err := s.rayService.GetRawStatus()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, codeStatusFailed, err)
+		return
+	}
+
+	includeRaw, _ := strconv.ParseBool(c.Query("raw"))
+
+	status, err := ray.ParseStatus(output)
+	if err != nil && !includeRaw {
+		respondError(c, http.StatusInternalServerError, codeStatusParseFailed, err)
+		return
+	}
+
+	resp := statusResponse{Status: status}
+	if includeRaw {
+		resp.Raw = output
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// startHead handles requests to start a Ray head node
+func (s *Server) startHead(c *gin.Context) {
+	var req startHeadRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	id, err := s.rayService.StartHead(req.Port)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondStartError(c, err)
 		return
 	}
 
+	c.JSON(http.StatusOK, startResponse{ID: id, Role: ray.RoleHead})
+}
+
+// startWorker handles requests to start a Ray worker node
+func (s *Server) startWorker(c *gin.Context) {
+	var req startWorkerRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	id, err := s.rayService.StartWorker(req.HeadIP, req.Port)
+	if err != nil {
+		respondStartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, startResponse{ID: id, Role: ray.RoleWorker})
+}
+
+// stopNode handles requests to stop the local Ray node
+func (s *Server) stopNode(c *gin.Context) {
+	if err := s.rayService.StopNode(); err != nil {
+		if errors.Is(err, ray.ErrNotRunning) {
+			respondError(c, http.StatusConflict, codeNotRunning, err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, codeStopFailed, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "stopped"})
+}
+
+// getRole handles requests for the applied role and reconciliation state
+func (s *Server) getRole(c *gin.Context) {
+	c.JSON(http.StatusOK, s.rayService.RoleState())
+}
+
+// getRoleHistory handles requests for recent role transitions
+func (s *Server) getRoleHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status": status,
+		"transitions": s.rayService.History(),
 	})
 }
+
+// getNode handles requests for this node's identity
+func (s *Server) getNode(c *gin.Context) {
+	c.JSON(http.StatusOK, s.resourceMgr.NodeInfo())
+}
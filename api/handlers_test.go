@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray/raytest"
+)
+
+// post sends a JSON body from localhost through s's router
+func post(s *Server, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "127.0.0.1:40000"
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeError decodes an error response
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorResponse {
+	t.Helper()
+	var resp errorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+	return resp
+}
+
+func TestStartHandlersValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		wantError string // Substring of the error message
+	}{
+		{name: "malformed body", path: "/start/head", body: `{"port":`, wantError: "unexpected EOF"},
+		{name: "port out of range", path: "/start/head", body: `{"port": 70000}`, wantError: "Port"},
+		{name: "port of the wrong type", path: "/start/head", body: `{"port": "6379"}`, wantError: "port"},
+		{name: "worker without head", path: "/start/worker", body: `{"port": 6379}`, wantError: "HeadIP"},
+		{name: "worker with an empty body", path: "/start/worker", body: "", wantError: "HeadIP"},
+		{name: "worker with an invalid head", path: "/start/worker", body: `{"headIP": "not a host"}`, wantError: "HeadIP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := raytest.NewFakeRunner()
+			s := newTestServer(t, fake)
+
+			w := post(s, tt.path, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("POST %s = %d, want 400", tt.path, w.Code)
+			}
+			resp := decodeError(t, w)
+			if resp.Code != codeInvalidRequest || !strings.Contains(resp.Error, tt.wantError) {
+				t.Errorf("error response = %+v, want %s mentioning %q", resp, codeInvalidRequest, tt.wantError)
+			}
+			if len(fake.Calls()) != 0 {
+				t.Errorf("Ray commands run for an invalid request: %v", fake.Subcommands())
+			}
+		})
+	}
+}
+
+func TestStartHeadAndStop(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestServer(t, fake)
+
+	w := post(s, "/start/head", "")
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":"head-6379","role":"head"}` {
+		t.Fatalf("POST /start/head = %d %s", w.Code, w.Body)
+	}
+
+	w = post(s, "/start/head", `{"port": 6380}`)
+	if resp := decodeError(t, w); w.Code != http.StatusConflict || resp.Code != codeAlreadyRunning {
+		t.Errorf("second POST /start/head = %d %+v, want 409 %s", w.Code, resp, codeAlreadyRunning)
+	}
+
+	w = post(s, "/stop", "")
+	if w.Code != http.StatusOK || w.Body.String() != `{"status":"stopped"}` {
+		t.Errorf("POST /stop = %d %s", w.Code, w.Body)
+	}
+	w = post(s, "/stop", "")
+	if resp := decodeError(t, w); w.Code != http.StatusConflict || resp.Code != codeNotRunning {
+		t.Errorf("second POST /stop = %d %+v, want 409 %s", w.Code, resp, codeNotRunning)
+	}
+
+	w = post(s, "/start/head", `{"port": 6380}`)
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":"head-6380","role":"head"}` {
+		t.Errorf("POST /start/head with a port = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestStartWorker(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestServer(t, fake)
+
+	// Fields of older clients, such as username and password, are ignored
+	w := post(s, "/start/worker", `{"headIP": "10.0.0.1", "port": 6380, "username": "rayuser", "password": "raypass"}`)
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":"worker-10-0-0-1","role":"worker"}` {
+		t.Fatalf("POST /start/worker = %d %s", w.Code, w.Body)
+	}
+	starts := 0
+	for _, call := range fake.Calls() {
+		if len(call.Args) > 0 && call.Args[0] == "start" {
+			starts++
+			if args := strings.Join(call.Args, " "); !strings.Contains(args, "--address 10.0.0.1:6380") || strings.Contains(args, "raypass") {
+				t.Errorf("worker started with %q", args)
+			}
+		}
+	}
+	if starts != 1 {
+		t.Errorf("%d starts, want 1", starts)
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	fake.On("start", raytest.Response{Output: "port 6379 already in use\n", Err: raytest.ErrExit})
+	s := newTestServer(t, fake)
+
+	w := post(s, "/start/head", "")
+	resp := decodeError(t, w)
+	if w.Code != http.StatusInternalServerError || resp.Code != codeStartFailed {
+		t.Fatalf("POST /start/head = %d %+v, want 500 %s", w.Code, resp, codeStartFailed)
+	}
+	if resp.Output != "port 6379 already in use" || !strings.Contains(resp.Error, "exit status 1") {
+		t.Errorf("error response = %+v, want the command error and its output", resp)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestServer(t, fake)
+
+	w := serve(s, http.MethodGet, "/status")
+	if resp := decodeError(t, w); w.Code != http.StatusInternalServerError || resp.Code != codeStatusFailed {
+		t.Errorf("GET /status while stopped = %d %+v, want 500 %s", w.Code, resp, codeStatusFailed)
+	}
+
+	fake.SetRunning(true)
+	w = serve(s, http.MethodGet, "/status")
+	var resp statusResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /status = %d %s", w.Code, w.Body)
+	}
+	if resp.Status == nil || len(resp.Status.ActiveNodes) == 0 || resp.Raw != "" {
+		t.Errorf("GET /status = %+v, want parsed nodes without raw output", resp)
+	}
+
+	w = serve(s, http.MethodGet, "/status?raw=true")
+	resp = statusResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /status?raw=true = %d %s", w.Code, w.Body)
+	}
+	if resp.Status == nil || resp.Raw != raytest.StatusOutput {
+		t.Errorf("GET /status?raw=true = %+v, want the parsed and raw output", resp)
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/health"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray"
+)
+
+// rayStatusMaxAge is how old a Ray running check may be for readiness; the
+// heartbeat and role loops refresh it every minute
+const rayStatusMaxAge = 2 * time.Minute
+
+// healthz reports whether the process is alive and its background loops are
+// making progress
+func (s *Server) healthz(c *gin.Context) {
+	checks := append([]health.Check{health.Pass("process", "")}, s.watchdog.Checks()...)
+	respondHealth(c, health.NewReport(checks...))
+}
+
+// readyz reports whether the node is registered, has applied its role, runs
+// Ray as that role requires and keeps its background loops going, since a
+// stalled heartbeat or role loop leaves the manager's view stale. Probes
+// skip the IP restriction, so the report leaves out the node ID and error
+// details that /node and /role give allowed clients.
+func (s *Server) readyz(c *gin.Context) {
+	checks := []health.Check{s.checkRegistered(), s.checkRole(), s.checkRay()}
+	respondHealth(c, health.NewReport(append(checks, s.watchdog.Checks()...)...))
+}
+
+// respondHealth writes report with 200 if it passed and 503 otherwise
+func respondHealth(c *gin.Context, report health.Report) {
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// checkRegistered passes once the node is registered with the manager
+func (s *Server) checkRegistered() health.Check {
+	const name = "registered"
+	if s.config.Load().ManagerIP == "" {
+		return health.Pass(name, "no manager configured")
+	}
+	if !s.resourceMgr.IsRegistered() {
+		return health.Fail(name, "not registered with the manager")
+	}
+	return health.Pass(name, "registered")
+}
+
+// checkRole passes once the role assigned by the manager has been applied
+func (s *Server) checkRole() health.Check {
+	const name = "role"
+	state := s.rayService.RoleState()
+	if state.Applied != nil {
+		return health.Pass(name, "applied "+string(state.Applied.Role))
+	}
+	if s.config.Load().ManagerIP == "" {
+		return health.Pass(name, "no manager configured")
+	}
+	if state.LastError != "" {
+		return health.Fail(name, "role change failed, see /role")
+	}
+	return health.Fail(name, "role not applied yet")
+}
+
+// checkRay passes when Ray runs if the applied role needs it
+func (s *Server) checkRay() health.Check {
+	const name = "ray"
+	state := s.rayService.RoleState()
+	if state.Applied == nil || state.Applied.Role == ray.RoleNone {
+		return health.Pass(name, "no role requires Ray")
+	}
+	if !s.rayService.RunningWithin(rayStatusMaxAge) {
+		return health.Fail(name, fmt.Sprintf("Ray is not running but role is %s", state.Applied.Role))
+	}
+	return health.Pass(name, "running")
+}
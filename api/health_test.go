@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/health"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager/managertest"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray/raytest"
+)
+
+// probe requests a health endpoint and decodes its report, keyed by check
+func probe(t *testing.T, s *Server, path string) (int, map[string]health.Check, string) {
+	t.Helper()
+	w := serve(s, http.MethodGet, path)
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decoding %s: %v", path, err)
+	}
+	checks := make(map[string]health.Check)
+	for _, c := range report.Checks {
+		checks[c.Name] = c
+	}
+	if report.OK() != (w.Code == http.StatusOK) {
+		t.Errorf("%s: status %q with code %d", path, report.Status, w.Code)
+	}
+	return w.Code, checks, w.Body.String()
+}
+
+func TestHealthz(t *testing.T) {
+	s := newTestServer(t, raytest.NewFakeRunner())
+
+	code, checks, _ := probe(t, s, "/healthz")
+	if code != http.StatusOK || checks["process"].Status != health.StatusOK {
+		t.Fatalf("healthz = %d %+v, want ok", code, checks)
+	}
+
+	s.watchdog.Loop("role").Beat(time.Hour)
+	s.watchdog.Loop("heartbeat").Beat(-time.Second) // Deadline already missed
+	code, checks, _ = probe(t, s, "/healthz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("healthz with a stalled loop = %d, want 503", code)
+	}
+	if c := checks["loop:heartbeat"]; c.Status != health.StatusFail || !strings.HasPrefix(c.Message, "no progress") {
+		t.Errorf("stalled loop check = %+v", c)
+	}
+	if c := checks["loop:role"]; c.Status != health.StatusOK {
+		t.Errorf("beating loop check = %+v", c)
+	}
+}
+
+func TestHealthProbesSkipIPRestriction(t *testing.T) {
+	s := newTestServer(t, raytest.NewFakeRunner())
+	for _, path := range []string{"/healthz", "/readyz"} {
+		if w := serveFrom(s, http.MethodGet, path, "203.0.113.9"); w.Code != http.StatusOK {
+			t.Errorf("%s from a disallowed IP = %d, want 200", path, w.Code)
+		}
+	}
+	if w := serveFrom(s, http.MethodGet, "/node", "203.0.113.9"); w.Code != http.StatusForbidden {
+		t.Errorf("/node from a disallowed IP = %d, want 403", w.Code)
+	}
+}
+
+func TestReadyzStandalone(t *testing.T) {
+	s := newTestServer(t, raytest.NewFakeRunner())
+
+	code, checks, _ := probe(t, s, "/readyz")
+	if code != http.StatusOK {
+		t.Fatalf("readyz without a manager = %d %+v, want 200", code, checks)
+	}
+	for _, name := range []string{"registered", "role", "ray"} {
+		if checks[name].Status != health.StatusOK {
+			t.Errorf("check %s = %+v, want ok", name, checks[name])
+		}
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	fake := raytest.NewFakeRunner()
+	fake.On("start", raytest.Response{Output: "secret ray output", Err: raytest.ErrExit})
+	s := newTestServer(t, fake, func(c *config.Config) { c.ManagerIP = srv.URL })
+	ctx := context.Background()
+
+	wantReadyz := func(step string, wantCode int, want map[string]string) string {
+		t.Helper()
+		code, checks, body := probe(t, s, "/readyz")
+		if code != wantCode {
+			t.Errorf("%s: readyz = %d, want %d", step, code, wantCode)
+		}
+		for name, message := range want {
+			if c := checks[name]; c.Message != message {
+				t.Errorf("%s: check %s = %+v, want %q", step, name, c, message)
+			}
+		}
+		return body
+	}
+
+	wantReadyz("unregistered", http.StatusServiceUnavailable, map[string]string{
+		"registered": "not registered with the manager",
+		"role":       "role not applied yet",
+		"ray":        "no role requires Ray",
+	})
+
+	if err := s.resourceMgr.RegisterNode(ctx); err != nil {
+		t.Fatalf("RegisterNode: %v", err)
+	}
+	if _, err := s.rayService.Reconcile(ctx, ray.RoleInfo{Role: ray.RoleHead}, ray.SourceManager); err == nil {
+		t.Fatal("Reconcile succeeded with a failing start")
+	}
+	body := wantReadyz("role failed", http.StatusServiceUnavailable, map[string]string{
+		"registered": "registered",
+		"role":       "role change failed, see /role",
+	})
+	for _, secret := range []string{s.resourceMgr.NodeID(), "secret ray output", "exit"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("readyz leaks %q: %s", secret, body)
+		}
+	}
+
+	worker := ray.RoleInfo{Role: ray.RoleWorker, HeadIP: "10.0.0.1"}
+	if _, err := s.rayService.Reconcile(ctx, worker, ray.SourceManager); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	wantReadyz("role applied", http.StatusOK, map[string]string{
+		"role": "applied worker",
+		"ray":  "running",
+	})
+
+	fake.SetRunning(false)
+	s.rayService.IsRunning() // Refresh the cached state
+	wantReadyz("Ray stopped", http.StatusServiceUnavailable, map[string]string{
+		"ray": "Ray is not running but role is worker",
+	})
+
+	fake.SetRunning(true)
+	s.rayService.IsRunning()
+	s.watchdog.Loop("heartbeat").Beat(-time.Second)
+	code, checks, _ := probe(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable || checks["loop:heartbeat"].Status != health.StatusFail {
+		t.Errorf("readyz with a stalled loop = %d %+v, want 503 with the loop failing", code, checks)
+	}
+}
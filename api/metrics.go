@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/metrics"
+	"github.com/unicornultrafoundation/subnet-rayai-node/resource"
+)
+
+// mib is the size of the unit GPU memory is reported in
+const mib = 1 << 20
+
+// newDesc describes a node metric
+func newDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "", name), help, labels, nil)
+}
+
+var (
+	cpusDesc             = newDesc("cpus", "Usable CPUs, capped by the cgroup quota.")
+	hostCPUsDesc         = newDesc("host_cpus", "CPUs of the host.")
+	cpuQuotaDesc         = newDesc("cpu_quota", "CPUs allowed by the cgroup, 0 if unlimited.")
+	memoryTotalDesc      = newDesc("memory_total_bytes", "Usable memory, capped by the cgroup limit.")
+	memoryFreeDesc       = newDesc("memory_free_bytes", "Available memory, including reclaimable cache.")
+	hostMemoryTotalDesc  = newDesc("host_memory_total_bytes", "Memory of the host.")
+	hostMemoryAvailDesc  = newDesc("host_memory_available_bytes", "Available memory of the host.")
+	memoryLimitDesc      = newDesc("memory_limit_bytes", "Memory limit of the cgroup, 0 if unlimited.")
+	memoryWorkingSetDesc = newDesc("memory_working_set_bytes", "Memory used by the cgroup, minus inactive cache.")
+
+	diskTotalDesc       = newDesc("disk_total_bytes", "Size of the filesystem holding a volume.", "name", "path")
+	diskFreeDesc        = newDesc("disk_free_bytes", "Free space on the filesystem holding a volume.", "name", "path")
+	diskAvailableDesc   = newDesc("disk_available_bytes", "Space available to unprivileged users on a volume.", "name", "path")
+	diskInodesTotalDesc = newDesc("disk_inodes_total", "Inodes of the filesystem holding a volume.", "name", "path")
+	diskInodesFreeDesc  = newDesc("disk_inodes_free", "Free inodes on the filesystem holding a volume.", "name", "path")
+
+	gpusDesc              = newDesc("gpus", "Detected GPUs.", "vendor")
+	gpuInfoDesc           = newDesc("gpu_info", "GPU identity, always 1.", "index", "uuid", "model", "driver_version")
+	gpuMemoryTotalDesc    = newDesc("gpu_memory_total_bytes", "GPU memory.", "index")
+	gpuMemoryFreeDesc     = newDesc("gpu_memory_free_bytes", "Free GPU memory.", "index")
+	gpuUtilizationDesc    = newDesc("gpu_utilization_ratio", "GPU utilization between 0 and 1.", "index")
+	gpuTemperatureDesc    = newDesc("gpu_temperature_celsius", "GPU temperature.", "index")
+	gpuPowerDrawDesc      = newDesc("gpu_power_draw_watts", "GPU power draw.", "index")
+	resourcesGatheredDesc = newDesc("resources_gathered", "Whether resources could be gathered.")
+	bandwidthDownloadDesc = newDesc("bandwidth_download_bits_per_second", "Internet download speed from the last speed test.")
+	bandwidthUploadDesc   = newDesc("bandwidth_upload_bits_per_second", "Internet upload speed from the last speed test.")
+	bandwidthPingDesc     = newDesc("bandwidth_ping_seconds", "Latency to the speed test server.")
+	bandwidthMeasuredDesc = newDesc("bandwidth_measured_timestamp_seconds", "Unix time of the last speed test.")
+
+	peerUpDesc       = newDesc("peer_up", "Whether the last measurement of a peer succeeded.", "peer")
+	peerRTTDesc      = newDesc("peer_rtt_seconds", "Mean round-trip time to a peer.", "peer")
+	peerJitterDesc   = newDesc("peer_jitter_seconds", "Mean difference between consecutive round trips to a peer.", "peer")
+	peerDownloadDesc = newDesc("peer_download_bits_per_second", "Throughput downloading from a peer.", "peer")
+	peerUploadDesc   = newDesc("peer_upload_bits_per_second", "Throughput uploading to a peer.", "peer")
+	peerMeasuredDesc = newDesc("peer_measured_timestamp_seconds", "Unix time of the last measurement of a peer.", "peer")
+)
+
+// resourceCollector exports the resource manager's figures at scrape time.
+// Resources are refreshed at most once per update interval; bandwidth
+// figures are whatever the background probes last measured.
+type resourceCollector struct {
+	mgr *resource.Manager
+}
+
+// Describe implements prometheus.Collector
+func (c resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		cpusDesc, hostCPUsDesc, cpuQuotaDesc,
+		memoryTotalDesc, memoryFreeDesc, hostMemoryTotalDesc, hostMemoryAvailDesc,
+		memoryLimitDesc, memoryWorkingSetDesc,
+		diskTotalDesc, diskFreeDesc, diskAvailableDesc, diskInodesTotalDesc, diskInodesFreeDesc,
+		gpusDesc, gpuInfoDesc, gpuMemoryTotalDesc, gpuMemoryFreeDesc,
+		gpuUtilizationDesc, gpuTemperatureDesc, gpuPowerDrawDesc,
+		resourcesGatheredDesc,
+		bandwidthDownloadDesc, bandwidthUploadDesc, bandwidthPingDesc, bandwidthMeasuredDesc,
+		peerUpDesc, peerRTTDesc, peerJitterDesc, peerDownloadDesc, peerUploadDesc, peerMeasuredDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c resourceCollector) Collect(ch chan<- prometheus.Metric) {
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+
+	res, err := c.mgr.GetResources(false)
+	gauge(resourcesGatheredDesc, metrics.BoolValue(err == nil))
+	if err == nil {
+		gauge(cpusDesc, float64(res.CPUCount))
+		gauge(hostCPUsDesc, float64(res.HostCPUCount))
+		gauge(cpuQuotaDesc, res.CPUQuota)
+		gauge(memoryTotalDesc, float64(res.MemoryTotal))
+		gauge(memoryFreeDesc, float64(res.MemoryFree))
+		gauge(hostMemoryTotalDesc, float64(res.HostMemoryTotal))
+		gauge(hostMemoryAvailDesc, float64(res.HostMemoryAvailable))
+		gauge(memoryLimitDesc, float64(res.MemoryLimit))
+		gauge(memoryWorkingSetDesc, float64(res.MemoryWorkingSet))
+
+		for _, disk := range res.Disks {
+			if disk.Error != "" {
+				continue
+			}
+			gauge(diskTotalDesc, float64(disk.Total), disk.Name, disk.Path)
+			gauge(diskFreeDesc, float64(disk.Free), disk.Name, disk.Path)
+			gauge(diskAvailableDesc, float64(disk.Available), disk.Name, disk.Path)
+			gauge(diskInodesTotalDesc, float64(disk.InodesTotal), disk.Name, disk.Path)
+			gauge(diskInodesFreeDesc, float64(disk.InodesFree), disk.Name, disk.Path)
+		}
+
+		gauge(gpusDesc, float64(res.GPUCount), res.GPUVendor)
+		for _, gpu := range res.GPUs {
+			index := strconv.Itoa(gpu.Index)
+			gauge(gpuInfoDesc, 1, index, gpu.UUID, gpu.Model, gpu.DriverVersion)
+			gauge(gpuMemoryTotalDesc, float64(gpu.MemoryTotal*mib), index)
+			gauge(gpuMemoryFreeDesc, float64(gpu.MemoryFree*mib), index)
+			gauge(gpuUtilizationDesc, gpu.Utilization/100, index)
+			// Zero means the tool did not report these
+			if gpu.Temperature != 0 {
+				gauge(gpuTemperatureDesc, gpu.Temperature, index)
+			}
+			if gpu.PowerDraw != 0 {
+				gauge(gpuPowerDrawDesc, gpu.PowerDraw, index)
+			}
+		}
+	}
+
+	if bw, err := c.mgr.GetBandwidth(context.Background(), false); err == nil {
+		gauge(bandwidthDownloadDesc, bw.DownloadMbps*1e6)
+		gauge(bandwidthUploadDesc, bw.UploadMbps*1e6)
+		gauge(bandwidthPingDesc, float64(bw.PingMs)/1e3)
+		gauge(bandwidthMeasuredDesc, float64(bw.MeasuredAt))
+	}
+
+	for _, peer := range c.mgr.PeerBandwidth() {
+		gauge(peerUpDesc, metrics.BoolValue(peer.Error == ""), peer.Peer)
+		gauge(peerMeasuredDesc, float64(peer.MeasuredAt), peer.Peer)
+		if peer.Error != "" {
+			continue
+		}
+		gauge(peerRTTDesc, peer.RTTMs/1e3, peer.Peer)
+		gauge(peerJitterDesc, peer.JitterMs/1e3, peer.Peer)
+		gauge(peerDownloadDesc, peer.DownloadMbps*1e6, peer.Peer)
+		gauge(peerUploadDesc, peer.UploadMbps*1e6, peer.Peer)
+	}
+}
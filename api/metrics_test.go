@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetricsScrape(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	w := serve(s, http.MethodGet, "/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", w.Code)
+	}
+	body := w.Body.String()
+
+	for _, want := range []string{
+		// Resource collector
+		"rayai_resources_gathered 1",
+		"rayai_cpus ",
+		"rayai_memory_total_bytes ",
+		`rayai_disk_total_bytes{name="data",path="` + s.config.Load().DataDir + `"}`,
+		`rayai_gpus{vendor=""} 0`,
+		// Process-wide registry
+		"go_goroutines ",
+		"rayai_ray_up ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+
+	// Nothing measured yet
+	for _, absent := range []string{"rayai_bandwidth_download_bits_per_second ", "rayai_peer_up{"} {
+		if strings.Contains(body, absent) {
+			t.Errorf("metrics have %q before any measurement", absent)
+		}
+	}
+}
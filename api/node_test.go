@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager/managertest"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray/raytest"
+)
+
+// getNode returns the decoded GET /node response
+func getNode(t *testing.T, s *Server) map[string]any {
+	t.Helper()
+	w := serve(s, http.MethodGet, "/node")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /node = %d %s", w.Code, w.Body)
+	}
+	var node map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &node); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+	return node
+}
+
+func TestGetNode(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	dataDir := t.TempDir()
+	withManager := func(c *config.Config) {
+		c.ManagerIP = srv.URL
+		c.DataDir = dataDir
+	}
+	s := newTestServer(t, raytest.NewFakeRunner(), withManager)
+	ctx := context.Background()
+	hostname, _ := os.Hostname()
+	publicKey := s.resourceMgr.PublicKey()
+
+	steps := []struct {
+		name   string
+		action func(t *testing.T)
+		want   map[string]any
+	}{
+		{
+			name: "before registration",
+			want: map[string]any{"public_key": publicKey, "registered": false, "hostname": hostname},
+		},
+		{
+			name: "registered by the first heartbeat",
+			action: func(t *testing.T) {
+				if err := s.resourceMgr.SendHeartbeat(ctx); err != nil {
+					t.Fatalf("SendHeartbeat: %v", err)
+				}
+			},
+			want: map[string]any{"node_id": "node-1", "public_key": publicKey, "registered": true, "hostname": hostname},
+		},
+		{
+			name: "registered again once the manager forgets the node",
+			action: func(t *testing.T) {
+				srv.Forget("node-1")
+				// The rejected heartbeat marks the node unregistered; the
+				// next one registers it again
+				_ = s.resourceMgr.SendHeartbeat(ctx)
+				if err := s.resourceMgr.SendHeartbeat(ctx); err != nil {
+					t.Fatalf("SendHeartbeat: %v", err)
+				}
+			},
+			want: map[string]any{"node_id": "node-2", "public_key": publicKey, "registered": true, "hostname": hostname},
+		},
+		{
+			name: "deregistered at shutdown",
+			action: func(t *testing.T) {
+				if err := s.resourceMgr.DeregisterNode(ctx); err != nil {
+					t.Fatalf("DeregisterNode: %v", err)
+				}
+			},
+			want: map[string]any{"node_id": "node-2", "public_key": publicKey, "registered": false, "hostname": hostname},
+		},
+		{
+			name: "identity reloaded after a restart",
+			action: func(t *testing.T) {
+				s = newTestServer(t, raytest.NewFakeRunner(), withManager)
+			},
+			want: map[string]any{"node_id": "node-2", "public_key": publicKey, "registered": true, "hostname": hostname},
+		},
+	}
+	for _, step := range steps {
+		if step.action != nil {
+			step.action(t)
+		}
+		if got := getNode(t, s); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: GET /node = %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	if regs := srv.Registrations(); len(regs) != 2 || regs[1].NodeID != "node-1" {
+		t.Errorf("registrations = %+v, want two, the second offering the old ID", regs)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/middleware"
+)
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 10 * time.Second
+
+// watchConfig reloads the configuration on SIGHUP and whenever the config
+// file changes, until ctx is done. A rejected reload is logged and the
+// previous configuration kept.
+func (s *Server) watchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+
+		path := s.config.Load().ConfigFile
+		stamp := fileStamp(path)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				s.log.Info("Received SIGHUP, reloading configuration")
+			case <-ticker.C:
+				if path == "" {
+					continue
+				}
+				current := fileStamp(path)
+				if current == stamp {
+					continue
+				}
+				stamp = current
+				s.log.Info("Config file changed, reloading configuration", "file", path)
+			}
+
+			if err := s.reloadConfig(); err != nil {
+				s.log.Error("Rejected configuration reload, keeping the previous configuration", "error", err)
+			}
+		}
+	}()
+}
+
+// reloadConfig loads the configuration again and, if only settings that can
+// change live differ, publishes it to every component at once
+func (s *Server) reloadConfig() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	current := s.config.Load()
+	next, err := current.Reload()
+	if err != nil {
+		return err
+	}
+	changed, err := current.LiveChanges(next)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		s.log.Info("Configuration unchanged")
+		return nil
+	}
+
+	ipFilter, err := middleware.NewIPFilter(next.AllowedIPs, next.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid IP restriction config: %w", err)
+	}
+	level, err := logging.ParseLevel(next.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	logging.SetLevel(level)
+	s.ipFilter.Set(ipFilter)
+	s.config.Set(next)
+	s.log.Info("Reloaded configuration", "changed", strings.Join(changed, ", "))
+	return nil
+}
+
+// fileStamp identifies the version of a file by its modification time and
+// size, empty if it can't be read
+func fileStamp(path string) string {
+	if path == "" {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+}
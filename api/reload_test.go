@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/middleware"
+)
+
+// newReloadTestServer returns a Server with just the parts reloadConfig
+// touches, configured from a config file holding content
+func newReloadTestServer(t *testing.T, content string) (*Server, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "node.yaml")
+	writeFile(t, path, content)
+
+	cfg, err := config.Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	filter, err := middleware.NewIPFilter(cfg.AllowedIPs, cfg.TrustedProxies)
+	if err != nil {
+		t.Fatalf("NewIPFilter: %v", err)
+	}
+	if err := logging.Setup(io.Discard, logging.FormatText, cfg.LogLevel); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	t.Cleanup(func() { logging.SetLevel(slog.LevelInfo) })
+
+	return &Server{
+		config:   config.NewStore(cfg),
+		log:      logging.For("api"),
+		ipFilter: middleware.NewReloadableIPFilter(filter),
+	}, path
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// allows reports whether the server's IP filter lets ip through
+func allows(s *Server, ip string) bool {
+	router := gin.New()
+	router.Use(s.ipFilter.Middleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = ip + ":40000"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code == http.StatusOK
+}
+
+func TestReloadConfigAppliesLiveSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s, path := newReloadTestServer(t, "allowed_ips: 127.0.0.1\nlog_level: info\nheartbeat_interval: 1m\n")
+	old := s.config.Load()
+	changed := s.config.Changed()
+
+	writeFile(t, path, "allowed_ips: 10.0.0.0/8\nlog_level: debug\nheartbeat_interval: 30s\n")
+	if err := s.reloadConfig(); err != nil {
+		t.Fatalf("reloadConfig: %v", err)
+	}
+
+	cfg := s.config.Load()
+	if cfg == old || cfg.LogLevel != "debug" || cfg.HeartbeatInterval.String() != "30s" {
+		t.Errorf("config after reload = %s, %s, want debug, 30s", cfg.LogLevel, cfg.HeartbeatInterval)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("components were not notified of the reload")
+	}
+	if !allows(s, "10.1.2.3") || allows(s, "127.0.0.1") {
+		t.Error("IP filter not replaced by the reloaded allowlist")
+	}
+	if !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("log level not lowered to debug")
+	}
+}
+
+func TestReloadConfigRejects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const initial = "allowed_ips: 127.0.0.1\nlog_level: info\napi_port: 3333\n"
+
+	tests := []struct {
+		name      string
+		content   string
+		wantError string
+	}{
+		{
+			name:      "restart-only setting",
+			content:   "allowed_ips: 10.0.0.0/8\nlog_level: debug\napi_port: 4000\n",
+			wantError: "API_PORT requires a restart",
+		},
+		{
+			name:      "invalid value",
+			content:   "allowed_ips: 10.0.0.0/8\nlog_level: loud\napi_port: 3333\n",
+			wantError: "LOG_LEVEL",
+		},
+		{
+			name:      "invalid allowlist",
+			content:   "allowed_ips: not-an-ip\nlog_level: debug\napi_port: 3333\n",
+			wantError: "not-an-ip",
+		},
+		{
+			name:      "unparsable file",
+			content:   "allowed_ips: [10.0.0.0/8\n",
+			wantError: "node.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, path := newReloadTestServer(t, initial)
+			old := s.config.Load()
+
+			writeFile(t, path, tt.content)
+			err := s.reloadConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Fatalf("reloadConfig = %v, want an error containing %q", err, tt.wantError)
+			}
+
+			// Nothing is applied, not even the valid live settings
+			if s.config.Load() != old {
+				t.Error("config replaced by a rejected reload")
+			}
+			if !allows(s, "127.0.0.1") || allows(s, "10.1.2.3") {
+				t.Error("IP filter changed by a rejected reload")
+			}
+			if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+				t.Error("log level changed by a rejected reload")
+			}
+		})
+	}
+}
+
+// Run with -race: requests keep reading the config and allowlist while
+// reloads replace them
+func TestReloadConfigConcurrentWithReaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s, path := newReloadTestServer(t, "allowed_ips: 127.0.0.1\nlog_level: info\n")
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				cfg := s.config.Load()
+				_ = cfg.LogLevel
+				_ = allows(s, "127.0.0.1")
+			}
+		}()
+	}
+
+	contents := []string{
+		"allowed_ips: 127.0.0.1,10.0.0.0/8\nlog_level: debug\n",
+		"allowed_ips: 127.0.0.1\nlog_level: info\n",
+	}
+	var reloads sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		writeFile(t, path, contents[i%2])
+		reloads.Add(2)
+		for j := 0; j < 2; j++ {
+			go func() {
+				defer reloads.Done()
+				if err := s.reloadConfig(); err != nil {
+					t.Errorf("reloadConfig: %v", err)
+				}
+			}()
+		}
+		reloads.Wait()
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -1,58 +1,240 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/health"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/metrics"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/middleware"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/tlsutil"
 	"github.com/unicornultrafoundation/subnet-rayai-node/ray"
 	"github.com/unicornultrafoundation/subnet-rayai-node/resource"
 )
 
+// deregisterTimeout bounds deregistration from the manager at shutdown
+const deregisterTimeout = 10 * time.Second
+
 // Server represents the API server
 type Server struct {
-	config      *config.Config
+	config      *config.Store // Live configuration, replaced on reload
+	log         *slog.Logger
 	router      *gin.Engine
 	rayService  *ray.Service
 	resourceMgr *resource.Manager
+	tlsConfig   *tls.Config // nil serves plain HTTP
+	ipFilter    *middleware.ReloadableIPFilter
+	watchdog    *health.Watchdog    // Background loops checked by /healthz
+	metrics     prometheus.Gatherer // Node-wide metrics and this server's resource figures
+
+	bandwidthSlots chan struct{} // Bounds concurrent bandwidth tests
+	reloadMu       sync.Mutex    // Serializes configuration reloads
 }
 
 // NewServer creates a new API server
-func NewServer(cfg *config.Config) *Server {
-	// Create Ray service
+func NewServer(cfg *config.Config) (*Server, error) {
+	// Parse the IP allowlist up front so misconfiguration fails at startup
+	ipFilter, err := middleware.NewIPFilter(cfg.AllowedIPs, cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP restriction config: %w", err)
+	}
+
+	// Load API certificates up front; they are reloaded when the files change
+	var tlsConfig *tls.Config
+	if cfg.TLSCertFile != "" {
+		tlsConfig, err = tlsutil.ServerConfig(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API TLS config: %w", err)
+		}
+	}
+
+	// Route gin's own debug output and request logs through the logger
+	logger := logging.For("api")
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		logger.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+	router := gin.New()
+	// Keep gin's own ClientIP (used in request logs) consistent with the filter
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(middleware.Logger(logger), middleware.Recovery(logger), middleware.Metrics())
+
+	// Components share the live configuration, replaced as a whole on reload
+	store := config.NewStore(cfg)
 
 	// Create Resource Manager
-	resourceMgr := resource.NewManager(cfg)
+	resourceMgr, err := resource.NewManager(store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource manager: %w", err)
+	}
+
+	// Create Ray service
+	rayService := ray.NewService(store, resourceMgr)
 
-	rayService := ray.NewService(cfg, resourceMgr)
+	// Report the Ray runtime state in heartbeats, and hold off bandwidth
+	// tests while Ray is running work
+	resourceMgr.SetRuntimeStateFunc(rayService.RuntimeState)
+	resourceMgr.SetBusyFunc(rayService.Busy)
 
-	// Create Gin router with default middleware
-	router := gin.Default()
+	// The resource collector reads this server's manager, so it is
+	// registered per server rather than on the process-wide registry
+	resourceMetrics := prometheus.NewRegistry()
+	if err := resourceMetrics.Register(resourceCollector{mgr: resourceMgr}); err != nil {
+		return nil, fmt.Errorf("failed to register resource metrics: %w", err)
+	}
 
 	server := &Server{
-		config:      cfg,
+		config:      store,
+		log:         logger,
 		router:      router,
 		rayService:  rayService,
 		resourceMgr: resourceMgr,
+		tlsConfig:   tlsConfig,
+		ipFilter:    middleware.NewReloadableIPFilter(ipFilter),
+		watchdog:    health.NewWatchdog(),
+		metrics:     prometheus.Gatherers{metrics.Registry, resourceMetrics},
+
+		bandwidthSlots: make(chan struct{}, maxConcurrentBandwidthTests),
 	}
 	server.setupRoutes()
 
-	// Start the resource manager background updater
-	resourceMgr.StartBackgroundUpdater()
-
-	// Start the resource manager heartbeat
-	resourceMgr.StartHeartbeat(time.Minute)
-
-	return server
+	return server, nil
 }
 
 // setupRoutes configures the API routes
 func (s *Server) setupRoutes() {
+	// Health probes come from the orchestrator, so they are registered
+	// before the IP restriction and client certificate checks apply
+	s.router.GET("/healthz", s.healthz)
+	s.router.GET("/readyz", s.readyz)
+
+	s.router.Use(s.ipFilter.Middleware())
+	if s.tlsConfig != nil && s.config.Load().TLSClientCAFile != "" {
+		s.router.Use(middleware.RequireClientCert())
+	}
+
 	s.router.GET("/status", s.getStatus)
+	s.router.POST("/start/head", s.startHead)
+	s.router.POST("/start/worker", s.startWorker)
+	s.router.POST("/stop", s.stopNode)
+	s.router.GET("/role", s.getRole)
+	s.router.GET("/role/history", s.getRoleHistory)
+	s.router.GET("/node", s.getNode)
+
+	// Peer bandwidth tests
+	s.router.GET("/bandwidth/ping", s.bandwidthPing)
+	s.router.GET("/bandwidth/download", s.bandwidthDownload)
+	s.router.POST("/bandwidth/upload", s.bandwidthUpload)
+	s.router.GET("/bandwidth/peers", s.getPeerBandwidth)
+
+	// Prometheus metrics
+	s.router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(s.metrics, promhttp.HandlerOpts{})))
 }
 
-// Run starts the API server
-func (s *Server) Run() error {
-	return s.router.Run(fmt.Sprintf(":%s", s.config.APIPort))
+// Run starts the background loops and serves the API until ctx is done,
+// then shuts the node down gracefully
+func (s *Server) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start the resource manager background updater
+	s.resourceMgr.StartBackgroundUpdater(ctx)
+
+	// Start the resource manager heartbeat
+	s.resourceMgr.StartHeartbeat(ctx, s.watchdog.Loop("heartbeat"))
+
+	// Start periodic role setup, which idles while no manager is configured
+	s.rayService.StartPeriodicRoleSetup(ctx, s.watchdog.Loop("role"))
+
+	// Reload the configuration on SIGHUP or when the config file changes
+	s.watchConfig(ctx)
+
+	httpServer := &http.Server{
+		Addr:      fmt.Sprintf(":%s", s.config.Load().APIPort),
+		Handler:   s.router,
+		TLSConfig: s.tlsConfig,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.tlsConfig != nil {
+			// Certificates come from TLSConfig.GetCertificate
+			serveErr <- httpServer.ListenAndServeTLS("", "")
+			return
+		}
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	var runErr error
+	select {
+	case err := <-serveErr:
+		runErr = fmt.Errorf("API server failed: %w", err)
+		cancel()
+	case <-ctx.Done():
+		s.log.Info("Shutdown requested")
+	}
+
+	s.shutdown(httpServer)
+	return runErr
+}
+
+// shutdown stops the API server and background loops within the configured
+// shutdown timeout, deregisters from the manager and applies the Ray
+// shutdown policy. Deregistration and Ray each get a deadline of their own,
+// so a slow earlier step can't leave the node registered or Ray running.
+func (s *Server) shutdown(httpServer *http.Server) {
+	timeout := s.config.Load().ShutdownTimeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(ctx); err != nil {
+		s.log.Warn("API server shutdown", "error", err)
+	}
+
+	// Wait for background loops so no heartbeat re-registers the node
+	// after it has been deregistered. Their context is already cancelled,
+	// which aborts any Ray command a role check is running.
+	loopsDone := make(chan struct{})
+	go func() {
+		s.resourceMgr.Wait()
+		s.rayService.Wait()
+		close(loopsDone)
+	}()
+	select {
+	case <-loopsDone:
+	case <-ctx.Done():
+		s.log.Warn("Timed out waiting for background loops to stop")
+	}
+
+	deregisterCtx, cancelDeregister := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancelDeregister()
+	if err := s.resourceMgr.DeregisterNode(deregisterCtx); err != nil {
+		s.log.Warn("Failed to deregister from manager", "error", err)
+	}
+
+	switch policy := s.config.Load().RayShutdownPolicy; policy {
+	case config.RayShutdownKeep:
+		s.log.Info("Leaving Ray running")
+	default:
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), timeout)
+		defer cancelDrain()
+		force := policy == config.RayShutdownForce
+		if err := s.rayService.Drain(drainCtx, force); err != nil {
+			s.log.Error("Failed to stop Ray", "error", err)
+		}
+	}
+
+	s.log.Info("Shutdown complete")
 }
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray"
+)
+
+// newTestServer returns a standalone Server over a temp data dir that skips
+// GPU and geolocation probes. A non-nil runner replaces the ray CLI.
+func newTestServer(t *testing.T, runner ray.Runner, mutate ...func(c *config.Config)) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cfg.ManagerIP = ""
+	cfg.DataDir = t.TempDir()
+	cfg.RayTempDir = t.TempDir()
+	cfg.DiskPaths = []string{"data=" + cfg.DataDir}
+	cfg.GPUVendor = config.GPUVendorNone
+	cfg.GeoProvider = config.GeoProviderNone
+	for _, m := range mutate {
+		m(cfg)
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if runner != nil {
+		s.rayService = ray.NewServiceWithRunner(s.config, s.resourceMgr, runner)
+		s.resourceMgr.SetRuntimeStateFunc(s.rayService.RuntimeState)
+		s.resourceMgr.SetBusyFunc(s.rayService.Busy)
+	}
+	return s
+}
+
+// serve sends a request from localhost through s's router
+func serve(s *Server, method, path string) *httptest.ResponseRecorder {
+	return serveFrom(s, method, path, "127.0.0.1")
+}
+
+// serveFrom sends a request from ip through s's router
+func serveFrom(s *Server, method, path, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":40000"
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestNewServerRepeatedly(t *testing.T) {
+	first := newTestServer(t, nil)
+	second := newTestServer(t, nil)
+
+	// Each server reports its own resources
+	for i, s := range []*Server{first, second} {
+		if w := serve(s, http.MethodGet, "/metrics"); w.Code != http.StatusOK {
+			t.Errorf("server %d: GET /metrics = %d", i, w.Code)
+		}
+	}
+}
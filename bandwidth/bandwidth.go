@@ -0,0 +1,263 @@
+// Package bandwidth measures throughput and latency between subnet peers
+// using the /bandwidth endpoints every node serves.
+package bandwidth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Payload sizes for download and upload tests
+const (
+	DefaultPayloadSize = 16 << 20  // 16 MiB
+	MaxPayloadSize     = 256 << 20 // Largest payload a node serves or accepts
+)
+
+// DefaultPings is the number of RTT probes per peer
+const DefaultPings = 5
+
+// blockSize is the size of the random block payloads are built from
+const blockSize = 64 << 10
+
+// block is random, hence incompressible, payload data
+var block = func() []byte {
+	b := make([]byte, blockSize)
+	rand.New(rand.NewSource(1)).Read(b)
+	return b
+}()
+
+// payloadReader yields n bytes of payload data
+type payloadReader struct {
+	remaining int64
+	offset    int
+}
+
+// NewPayloadReader returns a reader producing n bytes of generated,
+// incompressible data
+func NewPayloadReader(n int64) io.Reader {
+	return &payloadReader{remaining: n}
+}
+
+// Read implements io.Reader
+func (r *payloadReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], block[r.offset:])
+		n += copied
+		r.offset = (r.offset + copied) % blockSize
+	}
+	r.remaining -= int64(n)
+	return n, nil
+}
+
+// Result is the measured link to one peer. Error is set when the peer
+// could not be measured, in which case the other figures are zero.
+type Result struct {
+	Peer         string  `json:"peer"`
+	RTTMs        float64 `json:"rtt_ms"`     // Mean round-trip time
+	RTTMinMs     float64 `json:"rtt_min_ms"` // Fastest round trip
+	JitterMs     float64 `json:"jitter_ms"`  // Mean difference between consecutive round trips
+	DownloadMbps float64 `json:"download_mbps"`
+	UploadMbps   float64 `json:"upload_mbps"`
+	MeasuredAt   int64   `json:"measured_at"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// Client measures peers over HTTP
+type Client struct {
+	HTTP        *http.Client
+	PayloadSize int64 // Bytes per direction, DefaultPayloadSize if zero
+	Pings       int   // RTT probes per peer, DefaultPings if zero
+}
+
+// MeasureAll measures each peer in turn, so tests don't compete for the
+// node's own link
+func (c *Client) MeasureAll(ctx context.Context, peers []string) []Result {
+	results := make([]Result, 0, len(peers))
+	for _, peer := range peers {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, c.Measure(ctx, peer))
+	}
+	return results
+}
+
+// Measure runs RTT probes, a download and an upload against the node API
+// at baseURL, e.g. "http://10.0.0.5:3333"
+func (c *Client) Measure(ctx context.Context, baseURL string) Result {
+	result := Result{Peer: baseURL, MeasuredAt: time.Now().Unix()}
+
+	fail := func(err error) Result {
+		return Result{Peer: baseURL, MeasuredAt: result.MeasuredAt, Error: err.Error()}
+	}
+
+	rtts, err := c.ping(ctx, baseURL)
+	if err != nil {
+		return fail(err)
+	}
+	result.RTTMs, result.RTTMinMs, result.JitterMs = summarizeRTTs(rtts)
+
+	if result.DownloadMbps, err = c.download(ctx, baseURL); err != nil {
+		return fail(err)
+	}
+	if result.UploadMbps, err = c.upload(ctx, baseURL); err != nil {
+		return fail(err)
+	}
+	return result
+}
+
+// ping measures round trips to the peer's ping endpoint. An extra first
+// probe sets up the connection and is not counted.
+func (c *Client) ping(ctx context.Context, baseURL string) ([]time.Duration, error) {
+	pings := c.Pings
+	if pings <= 0 {
+		pings = DefaultPings
+	}
+
+	rtts := make([]time.Duration, 0, pings)
+	for i := 0; i <= pings; i++ {
+		start := time.Now()
+		if err := c.do(ctx, http.MethodGet, baseURL+"/bandwidth/ping", nil); err != nil {
+			return nil, fmt.Errorf("ping failed: %w", err)
+		}
+		if i > 0 {
+			rtts = append(rtts, time.Since(start))
+		}
+	}
+	return rtts, nil
+}
+
+// download fetches a payload from the peer, returning the throughput
+func (c *Client) download(ctx context.Context, baseURL string) (float64, error) {
+	size := c.payloadSize()
+	url := baseURL + "/bandwidth/download?bytes=" + strconv.FormatInt(size, 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("download failed with status: %s", resp.Status)
+	}
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("download failed: %w", err)
+	}
+	if n != size {
+		return 0, fmt.Errorf("download returned %d bytes, expected %d", n, size)
+	}
+	return mbps(n, time.Since(start)), nil
+}
+
+// upload sends a payload to the peer, returning the throughput
+func (c *Client) upload(ctx context.Context, baseURL string) (float64, error) {
+	size := c.payloadSize()
+	start := time.Now()
+	if err := c.do(ctx, http.MethodPost, baseURL+"/bandwidth/upload", NewPayloadReader(size)); err != nil {
+		return 0, fmt.Errorf("upload failed: %w", err)
+	}
+	return mbps(size, time.Since(start)), nil
+}
+
+// do sends a request and discards the response, failing on non-200 statuses
+func (c *Client) do(ctx context.Context, method, url string, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if payload, ok := body.(*payloadReader); ok {
+		req.ContentLength = payload.remaining
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, errResp.Error)
+		}
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// payloadSize returns the configured payload size, bounded to what peers
+// accept
+func (c *Client) payloadSize() int64 {
+	switch {
+	case c.PayloadSize <= 0:
+		return DefaultPayloadSize
+	case c.PayloadSize > MaxPayloadSize:
+		return MaxPayloadSize
+	default:
+		return c.PayloadSize
+	}
+}
+
+// summarizeRTTs returns the mean, minimum and jitter of the round trips in
+// milliseconds
+func summarizeRTTs(rtts []time.Duration) (mean, fastest, jitter float64) {
+	if len(rtts) == 0 {
+		return 0, 0, 0
+	}
+
+	fastest = math.MaxFloat64
+	var sum, diffs float64
+	for i, rtt := range rtts {
+		ms := durationMs(rtt)
+		sum += ms
+		if ms < fastest {
+			fastest = ms
+		}
+		if i > 0 {
+			diffs += math.Abs(ms - durationMs(rtts[i-1]))
+		}
+	}
+	mean = sum / float64(len(rtts))
+	if len(rtts) > 1 {
+		jitter = diffs / float64(len(rtts)-1)
+	}
+	return mean, fastest, jitter
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// mbps returns the throughput of n bytes transferred in d
+func mbps(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) * 8 / d.Seconds() / 1e6
+}
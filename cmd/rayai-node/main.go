@@ -1,23 +1,75 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/unicornultrafoundation/subnet-rayai-node/api"
 	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
 )
 
 func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(runConfigCommand(args[1:]))
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	logger := logging.For("main")
 
 	// Setup and run API server
-	server := api.NewServer(cfg)
-	log.Printf("Starting RayAI Node API server on port %s", cfg.APIPort)
-	if err := server.Run(); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	server, err := api.NewServer(cfg)
+	if err != nil {
+		logger.Error("Failed to create server", "error", err)
+		os.Exit(1)
+	}
+	// Stop gracefully on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("Starting RayAI Node API server", "port", cfg.APIPort)
+	if err := server.Run(ctx); err != nil {
+		logger.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// runConfigCommand handles `rayai-node config print [flags]`, returning the
+// exit code
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: rayai-node config print [flags]")
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+		return 1
 	}
+	return 0
 }
@@ -1,58 +1,352 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
-	"strconv"
 	"strings"
+	"time"
+)
+
+// Ray shutdown policies applied when the daemon exits
+const (
+	RayShutdownStop  = "stop"  // `ray stop`, letting Ray drain gracefully
+	RayShutdownForce = "force" // `ray stop --force`
+	RayShutdownKeep  = "keep"  // Leave Ray running
+)
+
+// GPU vendors selectable with GPU_VENDOR
+const (
+	GPUVendorAuto   = "auto"   // Try every vendor's tool in turn
+	GPUVendorNvidia = "nvidia" // nvidia-smi
+	GPUVendorAMD    = "amd"    // rocm-smi
+	GPUVendorIntel  = "intel"  // xpu-smi
+	GPUVendorNone   = "none"   // Skip GPU detection
+)
+
+// Geolocation providers selectable with GEO_PROVIDER
+const (
+	GeoProviderHTTP   = "http"   // Online lookup of the public IP
+	GeoProviderMMDB   = "mmdb"   // Local MaxMind/GeoLite2 database
+	GeoProviderStatic = "static" // Location configured by the operator
+	GeoProviderNone   = "none"   // Don't report a location
+)
+
+// Role failure policies selectable with ROLE_FAILURE_POLICY, applied when
+// the manager can't be asked for a role
+const (
+	RoleFailureKeep    = "keep"    // Keep the last role the manager assigned
+	RoleFailureIdle    = "idle"    // Stop Ray until the manager answers again
+	RoleFailureDefault = "default" // Run ROLE_FAILURE_DEFAULT
+)
+
+// Log levels selectable with LOG_LEVEL
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// Log output formats selectable with LOG_FORMAT
+const (
+	LogFormatText = "text" // logfmt-style key=value pairs
+	LogFormatJSON = "json" // One JSON object per record
 )
 
 // Config holds the application configuration
 type Config struct {
-	APIPort     string
-	RayBinPath  string
-	LogLevel    string
-	AllowedIPs  []string
-	RayHeadPort int // New field for Ray head node port
-	ManagerIP   string
+	ConfigFile string // Config file the values were read from, if any
+
+	APIPort           string
+	RayBinPath        string
+	RayCommandTimeout time.Duration // Upper bound for a single Ray CLI invocation
+	LogLevel          string
+	LogFormat         string // "text" or "json"
+	AllowedIPs        []string
+	TrustedProxies    []string // Peers allowed to set X-Forwarded-For/X-Real-IP
+	RayHeadPort       int      // New field for Ray head node port
+	ManagerIP         string
+	DataDir           string        // Directory for persistent node state
+	HeartbeatInterval time.Duration // Time between heartbeats to the manager
+	GPUVendor         string        // GPU detection backend, one of the GPUVendor constants
+	DiskPaths         []string      // Volumes to report, as "path" or "name=path"
+
+	// Bandwidth tests against subnet peers
+	BandwidthPeers        []string      // Extra peers, as host, host:port or URL of their node API
+	BandwidthPeerInterval time.Duration // Time between measurement rounds, 0 disables them
+	BandwidthPayloadSize  int64         // Bytes transferred per direction and peer
+
+	// Scheduling of expensive background probes
+	BandwidthInterval time.Duration // Time between internet speed tests, 0 disables them
+	GeoInterval       time.Duration // Time between geolocation lookups
+	ProbeJitter       float64       // Random spread applied to probe intervals, as a fraction
+	ProbeWhenIdle     bool          // Defer bandwidth tests while Ray is running work
+
+	// Geolocation reported at registration
+	GeoProvider  string // One of the GeoProvider constants
+	GeoHTTPURL   string // Lookup endpoint for the http provider
+	GeoMMDBPath  string // City database for the mmdb provider
+	GeoIP        string // Public IP, looked up by mmdb and reported by static
+	GeoCity      string
+	GeoRegion    string
+	GeoCountry   string
+	GeoLatitude  *float64
+	GeoLongitude *float64
+
+	// TLS for the node API; a client CA enables mutual TLS
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	// TLS for manager connections, used when ManagerIP is an https:// URL
+	ManagerCAFile         string // Replaces the system roots when set
+	ManagerClientCertFile string
+	ManagerClientKeyFile  string
+
+	// TLS for peer bandwidth tests, separate from the manager's so peers
+	// neither receive the manager client certificate nor pass as the manager
+	PeerCAFile         string // Replaces the system roots when set
+	PeerClientCertFile string
+	PeerClientKeyFile  string
+
+	// Behaviour while the manager can't be asked for a role
+	RoleFailurePolicy  string        // "keep", "idle" or "default"
+	RoleFailureDefault string        // Role run by the "default" policy, "head" or "none"
+	RoleFailureGrace   time.Duration // How long failures are tolerated before the policy applies
+
+	// Shutdown behaviour
+	ShutdownTimeout   time.Duration // Deadline for draining the API and background loops
+	RayShutdownPolicy string        // "stop", "force" or "keep" Ray running on exit
+
+	// Parameters passed to `ray start`; zero values keep Ray's defaults.
+	// Resource amounts also cap what the manager may request.
+	RayDashboardHost     string
+	RayDashboardPort     int
+	RayClientServerPort  int
+	RayNumCPUs           *int
+	RayNumGPUs           *int
+	RayMemory            int64 // Bytes
+	RayObjectStoreMemory int64 // Bytes
+	RayResources         map[string]float64
+	RayTempDir           string
+
+	args    []string          // Command-line arguments, kept for Reload
+	sources map[string]string // Source of each setting, by environment variable
 }
 
-// Load returns a Config struct populated from the environment
-func Load() (*Config, error) {
-	// Default configuration
+// Config sources, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// maxPayloadSize mirrors bandwidth.MaxPayloadSize, the largest transfer a
+// peer accepts
+const maxPayloadSize = 256 << 20
+
+// Load returns the configuration built from the command-line arguments
+// (without the program name), the environment, the config file given with
+// --config or CONFIG_FILE, and defaults, in that order of precedence. All
+// invalid values are reported together.
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("rayai-node", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file (env CONFIG_FILE)")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		key := s.key
+		usage := fmt.Sprintf("%s (env %s)", s.usage, key)
+		if s.def != "" {
+			usage += fmt.Sprintf(" (default %q)", s.def)
+		}
+		flags.Func(s.flagName(), usage, func(value string) error {
+			flagValues[key] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	var fileValues map[string]string
+	if *configFile != "" {
+		var err error
+		if fileValues, err = readFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
 	config := &Config{
-		APIPort:     getEnv("API_PORT", "3333"),    // Changed default from 8080 to 3333
-		RayBinPath:  getEnv("RAY_BIN_PATH", "ray"), // Default to system PATH
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
-		AllowedIPs:  parseAllowedIPs(getEnv("ALLOWED_IPS", "127.0.0.1")),
-		RayHeadPort: getEnvAsInt("RAY_HEAD_PORT", 6379), // Default Ray port
-		ManagerIP:   getEnv("MANAGER_IP", "10.0.0.4"),
+		ConfigFile: *configFile,
+		args:       args,
+		sources:    make(map[string]string, len(settings)),
+	}
+	var errs []error
+	for _, s := range settings {
+		value, source := s.def, SourceDefault
+		if v, ok := fileValues[s.key]; ok {
+			value, source = v, SourceFile
+		}
+		if v, ok := os.LookupEnv(s.key); ok {
+			value, source = v, SourceEnv
+		}
+		if v, ok := flagValues[s.key]; ok {
+			value, source = v, SourceFlag
+		}
+		if value == "" && !s.text {
+			value = s.def
+		}
+
+		if err := s.set(config, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q (from %s): %w", s.key, value, source, err))
+			continue
+		}
+		config.sources[s.key] = source
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
+	// Allow localhost when no IPs are allowed, and report the data and Ray
+	// temp dirs unless told otherwise
+	if len(config.AllowedIPs) == 0 {
+		config.AllowedIPs = []string{"127.0.0.1"}
+	}
+	if len(config.DiskPaths) == 0 {
+		config.DiskPaths = []string{"data=" + config.DataDir, "ray_temp=" + config.RayTempDir}
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
-// Helper functions for environment variables
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// validate checks the values that can't be judged one setting at a time,
+// or only by range
+func (c *Config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.RayHeadPort >= 1 && c.RayHeadPort <= 65535, "RAY_HEAD_PORT must be between 1 and 65535")
+	check(c.RayDashboardPort >= 0 && c.RayDashboardPort <= 65535, "RAY_DASHBOARD_PORT must be between 0 and 65535")
+	check(c.RayClientServerPort >= 0 && c.RayClientServerPort <= 65535, "RAY_CLIENT_SERVER_PORT must be between 0 and 65535")
+
+	if err := validateManagerIP(c.ManagerIP); err != nil {
+		errs = append(errs, fmt.Errorf("invalid MANAGER_IP %q: %w", c.ManagerIP, err))
+	}
+	for _, entry := range c.AllowedIPs {
+		if entry != "*" && !validNet(entry) {
+			errs = append(errs, fmt.Errorf("invalid ALLOWED_IPS entry %q: not an IP address or CIDR", entry))
+		}
+	}
+	for _, entry := range c.TrustedProxies {
+		if !validNet(entry) {
+			errs = append(errs, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: not an IP address or CIDR", entry))
+		}
+	}
+
+	check(c.RayCommandTimeout > 0, "RAY_COMMAND_TIMEOUT must be positive")
+	check(c.HeartbeatInterval > 0, "HEARTBEAT_INTERVAL must be positive")
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(c.GeoInterval > 0, "GEO_INTERVAL must be positive")
+	check(c.ProbeJitter >= 0 && c.ProbeJitter < 1, "invalid PROBE_JITTER %v, expected a fraction between 0 and 1", c.ProbeJitter)
+	check(c.BandwidthPayloadSize > 0 && c.BandwidthPayloadSize <= maxPayloadSize,
+		"BANDWIDTH_PAYLOAD_SIZE must be between 1 and %d bytes", maxPayloadSize)
+
+	check(c.RayNumCPUs == nil || *c.RayNumCPUs >= 0, "RAY_NUM_CPUS must not be negative")
+	check(c.RayNumGPUs == nil || *c.RayNumGPUs >= 0, "RAY_NUM_GPUS must not be negative")
+	check(c.RayMemory >= 0, "RAY_MEMORY must not be negative")
+	check(c.RayObjectStoreMemory >= 0, "RAY_OBJECT_STORE_MEMORY must not be negative")
+
+	switch c.GeoProvider {
+	case GeoProviderHTTP:
+		check(strings.HasPrefix(c.GeoHTTPURL, "https://"), "GEO_HTTP_URL must be an https:// URL")
+	case GeoProviderMMDB:
+		check(c.GeoMMDBPath != "", "GEO_PROVIDER=mmdb requires GEO_MMDB_PATH")
+	case GeoProviderStatic:
+		check(c.GeoCountry != "" || (c.GeoLatitude != nil && c.GeoLongitude != nil),
+			"GEO_PROVIDER=static requires GEO_COUNTRY or GEO_LATITUDE and GEO_LONGITUDE")
 	}
-	return defaultValue
+
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.TLSClientCAFile == "" || c.TLSCertFile != "", "TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	check((c.ManagerClientCertFile == "") == (c.ManagerClientKeyFile == ""),
+		"MANAGER_CLIENT_CERT_FILE and MANAGER_CLIENT_KEY_FILE must be set together")
+	check((c.PeerClientCertFile == "") == (c.PeerClientKeyFile == ""),
+		"PEER_CLIENT_CERT_FILE and PEER_CLIENT_KEY_FILE must be set together")
+
+	return errors.Join(errs...)
 }
 
-// getEnvAsInt parses an environment variable as an integer
-func getEnvAsInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// validateManagerIP accepts an empty value, host[:port] or an http(s) URL
+func validateManagerIP(value string) error {
+	if value == "" {
+		return nil
+	}
+	raw := value
+	if !strings.Contains(value, "://") {
+		raw = "http://" + value
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("expected an http:// or https:// URL")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("missing host")
+	}
+	if port := u.Port(); port != "" {
+		if _, err := parsePort(port); err != nil {
+			return err
 		}
 	}
-	return defaultValue
+	if !strings.Contains(value, "://") && u.Path != "" {
+		return fmt.Errorf("expected host[:port] or a URL")
+	}
+	return nil
 }
 
-// parseAllowedIPs parses comma-separated IPs into a slice
-func parseAllowedIPs(ips string) []string {
-	if ips == "" {
-		return []string{"127.0.0.1"} // Default to localhost
+// validNet reports whether entry is an IP address or CIDR range
+func validNet(entry string) bool {
+	if strings.Contains(entry, "/") {
+		_, _, err := net.ParseCIDR(entry)
+		return err == nil
+	}
+	if i := strings.IndexByte(entry, '%'); i >= 0 {
+		entry = entry[:i] // IPv6 zone
+	}
+	return net.ParseIP(entry) != nil
+}
+
+// Source returns where the value of the setting named by its environment
+// variable came from: SourceFlag, SourceEnv, SourceFile or SourceDefault
+func (c *Config) Source(key string) string {
+	return c.sources[key]
+}
+
+// ManagerURL returns the base URL of the manager. ManagerIP may be a bare
+// host[:port], implying plain HTTP, or a full http:// or https:// URL.
+func (c *Config) ManagerURL() string {
+	if c.ManagerIP == "" {
+		return ""
+	}
+	if strings.Contains(c.ManagerIP, "://") {
+		return strings.TrimSuffix(c.ManagerIP, "/")
 	}
-	return strings.Split(ips, ",")
+	return "http://" + c.ManagerIP
 }
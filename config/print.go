@@ -0,0 +1,61 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in printed values
+const redacted = "REDACTED"
+
+// Print writes the effective configuration as a YAML config file, noting
+// the source of each value. Credentials embedded in URLs are redacted.
+func (c *Config) Print(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	if c.ConfigFile != "" {
+		doc.HeadComment = "Read from " + c.ConfigFile
+	}
+	for _, s := range settings {
+		value := s.get(c)
+		if s.redact {
+			value = redactURL(value)
+		}
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.fileKey()},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, LineComment: c.Source(s.key)},
+		)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// redactURL hides the password and query values of a URL, which may hold
+// credentials or API keys
+func redactURL(value string) string {
+	if !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, redacted)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is one configuration option. It is read from the environment
+// variable key, from the config file as the lowercase key and from the
+// command line as the lowercase key with dashes, e.g. API_PORT, api_port
+// and --api-port.
+type setting struct {
+	key    string
+	def    string // Default, in the same text form as environment values
+	usage  string
+	text   bool // Empty values are meaningful; for other settings they select the default
+	redact bool // Value may embed credentials, hidden by Print
+	live   bool // Can change on reload without restarting the node
+	set    func(c *Config, value string) error
+	get    func(c *Config) string
+}
+
+// fileKey returns the name of the setting in config files
+func (s setting) fileKey() string {
+	return strings.ToLower(s.key)
+}
+
+// flagName returns the name of the setting's command-line flag
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.fileKey(), "_", "-")
+}
+
+// field builds a setting stored in the Config field returned by ptr
+func field[T any](key, def, usage string, ptr func(*Config) *T, parse func(string) (T, error), format func(T) string) setting {
+	return setting{
+		key:   key,
+		def:   def,
+		usage: usage,
+		set: func(c *Config, value string) error {
+			v, err := parse(value)
+			if err != nil {
+				return err
+			}
+			*ptr(c) = v
+			return nil
+		},
+		get: func(c *Config) string {
+			return format(*ptr(c))
+		},
+	}
+}
+
+// textField builds a setting whose empty value is meaningful
+func textField[T any](key, def, usage string, ptr func(*Config) *T, parse func(string) (T, error), format func(T) string) setting {
+	s := field(key, def, usage, ptr, parse, format)
+	s.text = true
+	return s
+}
+
+// stringSetting builds a free-form text setting
+func stringSetting(key, def, usage string, ptr func(*Config) *string) setting {
+	return textField(key, def, usage, ptr, parseString, formatString)
+}
+
+// urlSetting builds a text setting that may hold a URL with credentials
+func urlSetting(key, def, usage string, ptr func(*Config) *string) setting {
+	s := stringSetting(key, def, usage, ptr)
+	s.redact = true
+	return s
+}
+
+// choiceSetting builds a case-insensitive setting restricted to choices
+func choiceSetting(key, def, usage string, ptr func(*Config) *string, choices ...string) setting {
+	parse := func(value string) (string, error) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		for _, choice := range choices {
+			if value == choice {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("expected one of %s", strings.Join(choices, ", "))
+	}
+	return field(key, def, usage, ptr, parse, formatString)
+}
+
+// live marks a setting as safe to change on reload
+func live(s setting) setting {
+	s.live = true
+	return s
+}
+
+// settings lists every configuration option in documentation order
+var settings = []setting{
+	field("API_PORT", "3333", "Port for the API server", func(c *Config) *string { return &c.APIPort }, parsePort, formatString),
+	stringSetting("RAY_BIN_PATH", "ray", "Path to the Ray binary", func(c *Config) *string { return &c.RayBinPath }),
+	field("RAY_COMMAND_TIMEOUT", "2m", "Maximum duration of a single Ray CLI call", func(c *Config) *time.Duration { return &c.RayCommandTimeout }, parseDuration, formatDuration),
+	live(choiceSetting("LOG_LEVEL", LogLevelInfo, "Minimum level of logged records", func(c *Config) *string { return &c.LogLevel },
+		LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError)),
+	choiceSetting("LOG_FORMAT", LogFormatText, "Log output format", func(c *Config) *string { return &c.LogFormat },
+		LogFormatText, LogFormatJSON),
+	live(textField("ALLOWED_IPS", "127.0.0.1", "Comma-separated IPs/CIDRs allowed to call the API, * allows all", func(c *Config) *[]string { return &c.AllowedIPs }, parseListValue, formatList)),
+	textField("TRUSTED_PROXIES", "", "Comma-separated IPs/CIDRs of reverse proxies whose forwarding headers are honored", func(c *Config) *[]string { return &c.TrustedProxies }, parseListValue, formatList),
+	field("RAY_HEAD_PORT", "6379", "GCS port a head listens on and workers connect to", func(c *Config) *int { return &c.RayHeadPort }, parseInt, strconv.Itoa),
+	live(urlSetting("MANAGER_IP", "10.0.0.4", "Manager address as host[:port] or http(s):// URL, empty to run standalone", func(c *Config) *string { return &c.ManagerIP })),
+	stringSetting("DATA_DIR", "data", "Directory for persistent node state", func(c *Config) *string { return &c.DataDir }),
+	live(field("HEARTBEAT_INTERVAL", "1m", "Time between heartbeats to the manager", func(c *Config) *time.Duration { return &c.HeartbeatInterval }, parseDuration, formatDuration)),
+	live(choiceSetting("ROLE_FAILURE_POLICY", RoleFailureKeep, "Role run while the manager can't be reached", func(c *Config) *string { return &c.RoleFailurePolicy },
+		RoleFailureKeep, RoleFailureIdle, RoleFailureDefault)),
+	live(choiceSetting("ROLE_FAILURE_DEFAULT", "head", "Role run by the default failure policy", func(c *Config) *string { return &c.RoleFailureDefault },
+		"head", "none")),
+	live(field("ROLE_FAILURE_GRACE", "5m", "How long the manager may be unreachable before the failure policy applies", func(c *Config) *time.Duration { return &c.RoleFailureGrace }, parseDuration, formatDuration)),
+	choiceSetting("GPU_VENDOR", GPUVendorAuto, "GPU detection backend", func(c *Config) *string { return &c.GPUVendor },
+		GPUVendorAuto, GPUVendorNvidia, GPUVendorAMD, GPUVendorIntel, GPUVendorNone),
+	textField("DISK_PATHS", "", "Comma-separated volumes to report as path or name=path (default: data and Ray temp dirs)", func(c *Config) *[]string { return &c.DiskPaths }, parseListValue, formatList),
+
+	live(textField("BANDWIDTH_PEERS", "", "Comma-separated peers to measure, as host, host:port or node API URL", func(c *Config) *[]string { return &c.BandwidthPeers }, parseListValue, formatList)),
+	live(field("BANDWIDTH_PEER_INTERVAL", "1h", "Time between peer measurement rounds, 0 disables them", func(c *Config) *time.Duration { return &c.BandwidthPeerInterval }, parseDuration, formatDuration)),
+	field("BANDWIDTH_PAYLOAD_SIZE", "16777216", "Bytes transferred per direction and peer", func(c *Config) *int64 { return &c.BandwidthPayloadSize }, parseInt64, formatInt64),
+	live(field("BANDWIDTH_INTERVAL", "24h", "Time between internet speed tests, 0 disables them", func(c *Config) *time.Duration { return &c.BandwidthInterval }, parseDuration, formatDuration)),
+	live(field("GEO_INTERVAL", "4h", "Time between geolocation lookups", func(c *Config) *time.Duration { return &c.GeoInterval }, parseDuration, formatDuration)),
+	live(field("PROBE_JITTER", "0.1", "Random spread of probe intervals, as a fraction", func(c *Config) *float64 { return &c.ProbeJitter }, parseFloat, formatFloat)),
+	live(field("PROBE_WHEN_IDLE", "true", "Defer bandwidth tests while Ray is running work", func(c *Config) *bool { return &c.ProbeWhenIdle }, strconv.ParseBool, strconv.FormatBool)),
+
+	choiceSetting("GEO_PROVIDER", GeoProviderHTTP, "Location source", func(c *Config) *string { return &c.GeoProvider },
+		GeoProviderHTTP, GeoProviderMMDB, GeoProviderStatic, GeoProviderNone),
+	urlSetting("GEO_HTTP_URL", "https://ipapi.co/json/", "HTTPS lookup service for the http provider", func(c *Config) *string { return &c.GeoHTTPURL }),
+	stringSetting("GEO_MMDB_PATH", "", "City database for the mmdb provider", func(c *Config) *string { return &c.GeoMMDBPath }),
+	stringSetting("GEO_IP", "", "Public IP looked up by mmdb and reported by static", func(c *Config) *string { return &c.GeoIP }),
+	stringSetting("GEO_CITY", "", "City reported by the static provider", func(c *Config) *string { return &c.GeoCity }),
+	stringSetting("GEO_REGION", "", "Region reported by the static provider", func(c *Config) *string { return &c.GeoRegion }),
+	stringSetting("GEO_COUNTRY", "", "Country reported by the static provider", func(c *Config) *string { return &c.GeoCountry }),
+	field("GEO_LATITUDE", "", "Latitude reported by the static provider", func(c *Config) **float64 { return &c.GeoLatitude }, parseFloatPtr, formatFloatPtr),
+	field("GEO_LONGITUDE", "", "Longitude reported by the static provider", func(c *Config) **float64 { return &c.GeoLongitude }, parseFloatPtr, formatFloatPtr),
+
+	stringSetting("TLS_CERT_FILE", "", "Certificate to serve the API over HTTPS", func(c *Config) *string { return &c.TLSCertFile }),
+	stringSetting("TLS_KEY_FILE", "", "Private key for TLS_CERT_FILE", func(c *Config) *string { return &c.TLSKeyFile }),
+	stringSetting("TLS_CLIENT_CA_FILE", "", "CA that API client certificates must be signed by", func(c *Config) *string { return &c.TLSClientCAFile }),
+	stringSetting("MANAGER_CA_FILE", "", "CA bundle verifying an HTTPS manager instead of the system roots", func(c *Config) *string { return &c.ManagerCAFile }),
+	stringSetting("MANAGER_CLIENT_CERT_FILE", "", "Client certificate for mutual TLS with the manager", func(c *Config) *string { return &c.ManagerClientCertFile }),
+	stringSetting("MANAGER_CLIENT_KEY_FILE", "", "Private key for MANAGER_CLIENT_CERT_FILE", func(c *Config) *string { return &c.ManagerClientKeyFile }),
+	stringSetting("PEER_CA_FILE", "", "CA bundle verifying HTTPS peers in bandwidth tests instead of the system roots", func(c *Config) *string { return &c.PeerCAFile }),
+	stringSetting("PEER_CLIENT_CERT_FILE", "", "Client certificate presented to peers in bandwidth tests", func(c *Config) *string { return &c.PeerClientCertFile }),
+	stringSetting("PEER_CLIENT_KEY_FILE", "", "Private key for PEER_CLIENT_CERT_FILE", func(c *Config) *string { return &c.PeerClientKeyFile }),
+
+	live(field("SHUTDOWN_TIMEOUT", "30s", "Deadline for graceful shutdown", func(c *Config) *time.Duration { return &c.ShutdownTimeout }, parseDuration, formatDuration)),
+	live(choiceSetting("RAY_SHUTDOWN_POLICY", RayShutdownStop, "What to do with Ray on exit", func(c *Config) *string { return &c.RayShutdownPolicy },
+		RayShutdownStop, RayShutdownForce, RayShutdownKeep)),
+
+	stringSetting("RAY_DASHBOARD_HOST", "0.0.0.0", "--dashboard-host for head nodes", func(c *Config) *string { return &c.RayDashboardHost }),
+	field("RAY_DASHBOARD_PORT", "0", "--dashboard-port for head nodes, 0 for Ray's default", func(c *Config) *int { return &c.RayDashboardPort }, parseInt, strconv.Itoa),
+	field("RAY_CLIENT_SERVER_PORT", "0", "--ray-client-server-port for head nodes, 0 for Ray's default", func(c *Config) *int { return &c.RayClientServerPort }, parseInt, strconv.Itoa),
+	field("RAY_NUM_CPUS", "", "--num-cpus, caps CPUs contributed to the subnet", func(c *Config) **int { return &c.RayNumCPUs }, parseIntPtr, formatIntPtr),
+	field("RAY_NUM_GPUS", "", "--num-gpus, caps GPUs contributed to the subnet", func(c *Config) **int { return &c.RayNumGPUs }, parseIntPtr, formatIntPtr),
+	field("RAY_MEMORY", "0", "--memory in bytes", func(c *Config) *int64 { return &c.RayMemory }, parseInt64, formatInt64),
+	field("RAY_OBJECT_STORE_MEMORY", "0", "--object-store-memory in bytes", func(c *Config) *int64 { return &c.RayObjectStoreMemory }, parseInt64, formatInt64),
+	field("RAY_RESOURCES", "", `--resources as a JSON object, e.g. {"ssd": 1}`, func(c *Config) *map[string]float64 { return &c.RayResources }, parseResources, formatResources),
+	stringSetting("RAY_TEMP_DIR", "/tmp/ray", "--temp-dir for head nodes, also cleared on role changes", func(c *Config) *string { return &c.RayTempDir }),
+}
+
+// settingsByFileKey indexes settings by their config file key
+var settingsByFileKey = func() map[string]setting {
+	m := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		m[s.fileKey()] = s
+	}
+	return m
+}()
+
+func parseString(value string) (string, error) { return value, nil }
+func formatString(value string) string         { return value }
+
+// parsePort parses a TCP port
+func parsePort(value string) (string, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 1 || port > 65535 {
+		return "", fmt.Errorf("expected a port between 1 and 65535")
+	}
+	return strconv.Itoa(port), nil
+}
+
+func parseInt(value string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+func parseInt64(value string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+}
+
+func formatInt64(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+// parseIntPtr parses an integer, with empty meaning unset
+func parseIntPtr(value string) (*int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	v, err := parseInt(value)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func formatIntPtr(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func parseFloat(value string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(value), 64)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// parseFloatPtr parses a float, with empty meaning unset
+func parseFloatPtr(value string) (*float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	v, err := parseFloat(value)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func formatFloatPtr(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return formatFloat(*value)
+}
+
+// parseDuration parses a duration such as "90s" and rejects negative ones
+func parseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+func formatDuration(value time.Duration) string {
+	return value.String()
+}
+
+func parseListValue(value string) ([]string, error) {
+	return parseList(value), nil
+}
+
+func formatList(value []string) string {
+	return strings.Join(value, ",")
+}
+
+// parseResources parses a JSON object of custom Ray resources
+func parseResources(value string) (map[string]float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var resources map[string]float64
+	if err := json.Unmarshal([]byte(value), &resources); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of numbers: %w", err)
+	}
+	return resources, nil
+}
+
+// formatResources formats custom Ray resources as JSON with sorted keys
+func formatResources(value map[string]float64) string {
+	if len(value) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(value) // Map keys are sorted by encoding/json
+	return string(data)
+}
+
+// parseList parses a comma-separated list, returning nil when empty
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
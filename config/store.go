@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the live configuration shared by the node's components.
+// Replacing it publishes every reloaded value at once, so no component sees
+// a mix of old and new settings.
+type Store struct {
+	current atomic.Pointer[Config]
+
+	mu      sync.Mutex
+	changed chan struct{} // Closed and replaced on every Set
+}
+
+// NewStore returns a store holding cfg
+func NewStore(cfg *Config) *Store {
+	s := &Store{changed: make(chan struct{})}
+	s.current.Store(cfg)
+	return s
+}
+
+// Load returns the current configuration, which must not be modified
+func (s *Store) Load() *Config {
+	return s.current.Load()
+}
+
+// Set replaces the current configuration and wakes Changed waiters
+func (s *Store) Set(cfg *Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Store(cfg)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Changed returns a channel closed at the next Set
+func (s *Store) Changed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// Reload loads the configuration again from the same arguments, the
+// environment and the config file
+func (c *Config) Reload() (*Config, error) {
+	return Load(c.args)
+}
+
+// LiveChanges returns the settings that differ in next, by environment
+// variable. It fails if any of them can only be applied by a restart.
+func (c *Config) LiveChanges(next *Config) ([]string, error) {
+	var changed, restart []string
+	for _, s := range settings {
+		if s.get(c) == s.get(next) {
+			continue
+		}
+		if s.live {
+			changed = append(changed, s.key)
+		} else {
+			restart = append(restart, s.key)
+		}
+	}
+	if len(restart) > 0 {
+		return nil, fmt.Errorf("changing %s requires a restart", strings.Join(restart, ", "))
+	}
+	return changed, nil
+}
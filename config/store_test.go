@@ -0,0 +1,139 @@
+package config
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLiveChanges(t *testing.T) {
+	base := &Config{LogLevel: LogLevelInfo, AllowedIPs: []string{"127.0.0.1"}, APIPort: "3333", HeartbeatInterval: time.Minute}
+
+	tests := []struct {
+		name        string
+		mutate      func(c *Config)
+		wantChanged []string
+		wantRestart []string // Keys the error must name
+	}{
+		{
+			name:   "unchanged",
+			mutate: func(c *Config) {},
+		},
+		{
+			name: "live settings",
+			mutate: func(c *Config) {
+				c.LogLevel = LogLevelDebug
+				c.AllowedIPs = []string{"10.0.0.0/8"}
+				c.HeartbeatInterval = 30 * time.Second
+			},
+			wantChanged: []string{"LOG_LEVEL", "ALLOWED_IPS", "HEARTBEAT_INTERVAL"},
+		},
+		{
+			name:        "restart-only setting",
+			mutate:      func(c *Config) { c.APIPort = "4000" },
+			wantRestart: []string{"API_PORT"},
+		},
+		{
+			name: "restart-only setting beside live ones",
+			mutate: func(c *Config) {
+				c.LogLevel = LogLevelDebug
+				c.APIPort = "4000"
+			},
+			wantRestart: []string{"API_PORT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := *base
+			tt.mutate(&next)
+
+			changed, err := base.LiveChanges(&next)
+			if len(tt.wantRestart) > 0 {
+				if err == nil {
+					t.Fatalf("LiveChanges = %v, want a restart error", changed)
+				}
+				for _, key := range tt.wantRestart {
+					if !strings.Contains(err.Error(), key) {
+						t.Errorf("error %q doesn't name %s", err, key)
+					}
+				}
+				if strings.Contains(err.Error(), "LOG_LEVEL") {
+					t.Errorf("error %q names a live setting", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LiveChanges: %v", err)
+			}
+			if strings.Join(changed, ",") != strings.Join(tt.wantChanged, ",") {
+				t.Errorf("LiveChanges = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestStoreSetWakesChanged(t *testing.T) {
+	first := &Config{LogLevel: LogLevelInfo}
+	s := NewStore(first)
+	changed := s.Changed()
+
+	select {
+	case <-changed:
+		t.Fatal("Changed closed before Set")
+	default:
+	}
+
+	second := &Config{LogLevel: LogLevelDebug}
+	s.Set(second)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("Changed not closed by Set")
+	}
+	if s.Load() != second {
+		t.Error("Load doesn't return the config just set")
+	}
+	select {
+	case <-s.Changed():
+		t.Error("Changed after Set is already closed")
+	default:
+	}
+}
+
+// Run with -race: readers must never see a config being replaced
+func TestStoreConcurrentSetAndLoad(t *testing.T) {
+	s := NewStore(&Config{LogLevel: LogLevelInfo, AllowedIPs: []string{"127.0.0.1"}})
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-s.Changed():
+				default:
+				}
+				cfg := s.Load()
+				if (cfg.LogLevel == LogLevelDebug) != (cfg.AllowedIPs[0] == "10.0.0.1") {
+					t.Errorf("mixed config: %s, %v", cfg.LogLevel, cfg.AllowedIPs)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		if i%2 == 0 {
+			s.Set(&Config{LogLevel: LogLevelDebug, AllowedIPs: []string{"10.0.0.1"}})
+		} else {
+			s.Set(&Config{LogLevel: LogLevelInfo, AllowedIPs: []string{"127.0.0.1"}})
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
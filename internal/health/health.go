@@ -0,0 +1,136 @@
+// Package health tracks background loop liveness and formats the results
+// of health checks for the /healthz and /readyz endpoints.
+package health
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check is the outcome of one health check
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Pass returns a passing check
+func Pass(name, message string) Check {
+	return Check{Name: name, Status: StatusOK, Message: message}
+}
+
+// Fail returns a failing check
+func Fail(name, message string) Check {
+	return Check{Name: name, Status: StatusFail, Message: message}
+}
+
+// Report is the response of a health endpoint: ok only if every check is
+type Report struct {
+	Status string  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+// NewReport combines checks into a report
+func NewReport(checks ...Check) Report {
+	report := Report{Status: StatusOK, Checks: checks}
+	for _, check := range checks {
+		if check.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// OK reports whether every check passed
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Watchdog tracks background loops, each expected to beat before the
+// deadline it announced on its previous beat
+type Watchdog struct {
+	mu    sync.Mutex
+	loops map[string]*Loop
+}
+
+// NewWatchdog returns a watchdog without loops
+func NewWatchdog() *Watchdog {
+	return &Watchdog{loops: make(map[string]*Loop)}
+}
+
+// Loop registers a loop under name, replacing any previous one. Its
+// deadlines are checked from its first beat on.
+func (w *Watchdog) Loop(name string) *Loop {
+	l := &Loop{name: name}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.loops[name] = l
+	return l
+}
+
+// Checks returns one check per registered loop, sorted by name
+func (w *Watchdog) Checks() []Check {
+	w.mu.Lock()
+	loops := make([]*Loop, 0, len(w.loops))
+	for _, l := range w.loops {
+		loops = append(loops, l)
+	}
+	w.mu.Unlock()
+
+	sort.Slice(loops, func(i, j int) bool { return loops[i].name < loops[j].name })
+
+	checks := make([]Check, 0, len(loops))
+	for _, l := range loops {
+		checks = append(checks, l.check(time.Now()))
+	}
+	return checks
+}
+
+// Loop is a background loop tracked by a Watchdog. A nil Loop ignores beats,
+// so loops can run untracked.
+type Loop struct {
+	name string
+
+	mu       sync.Mutex
+	last     time.Time
+	deadline time.Time
+}
+
+// Beat records that the loop made progress and is expected to beat again
+// within the given duration
+func (l *Loop) Beat(within time.Duration) {
+	if l == nil {
+		return
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.last = now
+	l.deadline = now.Add(within)
+}
+
+// check reports whether the loop beat before its deadline
+func (l *Loop) check(now time.Time) Check {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	name := "loop:" + l.name
+	if l.last.IsZero() {
+		return Pass(name, "not started")
+	}
+	since := now.Sub(l.last).Round(time.Second)
+	if now.After(l.deadline) {
+		return Fail(name, fmt.Sprintf("no progress for %s", since))
+	}
+	return Pass(name, fmt.Sprintf("last progress %s ago", since))
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	if r := NewReport(Pass("a", ""), Pass("b", "")); !r.OK() || r.Status != StatusOK {
+		t.Errorf("report of passing checks = %+v, want ok", r)
+	}
+	if r := NewReport(Pass("a", ""), Fail("b", "down")); r.OK() || r.Status != StatusFail {
+		t.Errorf("report with a failing check = %+v, want fail", r)
+	}
+	if r := NewReport(); !r.OK() {
+		t.Errorf("empty report = %+v, want ok", r)
+	}
+}
+
+func TestLoopCheck(t *testing.T) {
+	w := NewWatchdog()
+	l := w.Loop("heartbeat")
+	now := time.Now()
+
+	if c := l.check(now); c.Status != StatusOK || c.Message != "not started" {
+		t.Errorf("check before the first beat = %+v, want ok, not started", c)
+	}
+
+	l.Beat(time.Minute)
+	if c := l.check(now.Add(30 * time.Second)); c.Status != StatusOK || c.Name != "loop:heartbeat" {
+		t.Errorf("check within the deadline = %+v, want ok", c)
+	}
+	c := l.check(now.Add(2 * time.Minute))
+	if c.Status != StatusFail || !strings.HasPrefix(c.Message, "no progress for 2m") {
+		t.Errorf("check past the deadline = %+v, want fail, no progress for 2m", c)
+	}
+
+	// A new beat moves the deadline
+	l.Beat(time.Hour)
+	if c := l.check(time.Now().Add(2 * time.Minute)); c.Status != StatusOK {
+		t.Errorf("check after a new beat = %+v, want ok", c)
+	}
+}
+
+func TestWatchdogFiresWhenLoopStops(t *testing.T) {
+	w := NewWatchdog()
+	role := w.Loop("role")
+	heartbeat := w.Loop("heartbeat")
+
+	// Both loops beat; the heartbeat loop then stops
+	role.Beat(time.Hour)
+	heartbeat.Beat(10 * time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	checks := w.Checks()
+	if len(checks) != 2 || checks[0].Name != "loop:heartbeat" || checks[1].Name != "loop:role" {
+		t.Fatalf("Checks = %+v, want heartbeat then role", checks)
+	}
+	if checks[0].Status != StatusFail {
+		t.Errorf("stopped loop = %+v, want fail", checks[0])
+	}
+	if checks[1].Status != StatusOK {
+		t.Errorf("beating loop = %+v, want ok", checks[1])
+	}
+	if NewReport(checks...).OK() {
+		t.Error("report with a stopped loop is ok")
+	}
+
+	// Registering a loop again replaces it
+	w.Loop("heartbeat")
+	if checks := w.Checks(); len(checks) != 2 || checks[0].Status != StatusOK {
+		t.Errorf("Checks after re-registering = %+v, want the new loop not started", checks)
+	}
+}
+
+func TestNilLoopIgnoresBeats(t *testing.T) {
+	var l *Loop
+	l.Beat(time.Minute) // Must not panic
+}
@@ -0,0 +1,126 @@
+// Package logging configures the node's structured logger. Records carry the
+// emitting component and, once known, the node ID and applied Ray role.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	// level is shared by every handler so LOG_LEVEL can change live
+	level = new(slog.LevelVar)
+
+	nodeID atomic.Value // string
+	role   atomic.Value // string
+)
+
+// Detailer is implemented by errors that carry context worth logging beside
+// the message, such as the output of a failed command
+type Detailer interface {
+	LogAttrs() []slog.Attr
+}
+
+// Setup installs the process-wide logger writing records to w in format.
+// The standard log package is routed through it at info level.
+func Setup(w io.Writer, format, levelName string) error {
+	l, err := ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch format {
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText, "":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	slog.SetDefault(slog.New(handler{h}))
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", name)
+	}
+	return l, nil
+}
+
+// SetLevel changes the minimum level of records logged
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// For returns the logger of a component, such as "ray" or "api"
+func For(component string) *slog.Logger {
+	return slog.Default().With("component", component)
+}
+
+// SetNodeID adds the node ID to every following record
+func SetNodeID(id string) {
+	nodeID.Store(id)
+}
+
+// SetRole adds the applied Ray role to every following record
+func SetRole(r string) {
+	role.Store(r)
+}
+
+// handler adds the node ID, role and error details to records
+type handler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler
+func (h handler) Handle(ctx context.Context, r slog.Record) error {
+	var extra []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if err, ok := a.Value.Any().(error); ok {
+			var d Detailer
+			if errors.As(err, &d) {
+				extra = append(extra, d.LogAttrs()...)
+			}
+		}
+		return true
+	})
+	if id, _ := nodeID.Load().(string); id != "" {
+		extra = append(extra, slog.String("node_id", id))
+	}
+	if applied, _ := role.Load().(string); applied != "" {
+		extra = append(extra, slog.String("role", applied))
+	}
+
+	if len(extra) > 0 {
+		r = r.Clone()
+		r.AddAttrs(extra...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h handler) WithGroup(name string) slog.Handler {
+	return handler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,179 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// detailedError carries command output like ray.CommandError
+type detailedError struct {
+	output string
+}
+
+func (e *detailedError) Error() string { return "exit status 1" }
+
+func (e *detailedError) LogAttrs() []slog.Attr {
+	return []slog.Attr{slog.String("output", e.output)}
+}
+
+// setupBuffer installs the logger writing JSON records to a buffer, undone
+// at the end of the test
+func setupBuffer(t *testing.T, levelName string) *bytes.Buffer {
+	t.Helper()
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		SetNodeID("")
+		SetRole("")
+	})
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, FormatJSON, levelName); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	return &buf
+}
+
+// records decodes the JSON records written to buf
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var recs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestHandlerAttachesErrorDetails(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantOutput string
+	}{
+		{name: "detailer", err: &detailedError{output: "port 6379 in use"}, wantOutput: "port 6379 in use"},
+		{name: "wrapped detailer", err: fmt.Errorf("failed to start Ray head node: %w", &detailedError{output: "port 6379 in use"}), wantOutput: "port 6379 in use"},
+		{name: "joined detailer", err: errors.Join(errors.New("stop failed"), &detailedError{output: "no ray"}), wantOutput: "no ray"},
+		{name: "plain error", err: errors.New("timeout")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := setupBuffer(t, "info")
+			For("ray").Error("Role change failed", "error", tt.err)
+
+			recs := records(t, buf)
+			if len(recs) != 1 {
+				t.Fatalf("%d records, want 1", len(recs))
+			}
+			rec := recs[0]
+			if rec["error"] != tt.err.Error() || rec["component"] != "ray" || rec["msg"] != "Role change failed" {
+				t.Errorf("record = %v", rec)
+			}
+			output, ok := rec["output"]
+			if tt.wantOutput == "" && ok {
+				t.Errorf("output %q attached to a plain error", output)
+			}
+			if tt.wantOutput != "" && output != tt.wantOutput {
+				t.Errorf("output = %v, want %q", output, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestHandlerAddsNodeIDAndRole(t *testing.T) {
+	buf := setupBuffer(t, "info")
+	logger := For("api")
+
+	logger.Info("Before registration")
+	SetNodeID("node-1")
+	SetRole("worker")
+	logger.With("request", 7).Info("After", "host", "10.0.0.2")
+
+	recs := records(t, buf)
+	if len(recs) != 2 {
+		t.Fatalf("%d records, want 2", len(recs))
+	}
+	if _, ok := recs[0]["node_id"]; ok {
+		t.Errorf("node ID before it is known: %v", recs[0])
+	}
+	rec := recs[1]
+	if rec["component"] != "api" || rec["request"] != float64(7) || rec["host"] != "10.0.0.2" {
+		t.Errorf("record = %v", rec)
+	}
+	if rec["node_id"] != "node-1" || rec["role"] != "worker" {
+		t.Errorf("record = %v, want node_id and role", rec)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	buf := setupBuffer(t, "warn")
+	logger := For("resource")
+
+	logger.Info("dropped")
+	logger.Warn("kept")
+	SetLevel(slog.LevelDebug)
+	logger.Debug("kept after lowering")
+	log.Print("standard log") // Routed through slog at info level
+
+	var msgs []string
+	for _, rec := range records(t, buf) {
+		msgs = append(msgs, fmt.Sprint(rec["msg"]))
+	}
+	if got := strings.Join(msgs, ","); got != "kept,kept after lowering,standard log" {
+		t.Errorf("logged %s", got)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, FormatText, "debug"); err != nil {
+		t.Fatalf("Setup text: %v", err)
+	}
+	For("ray").Debug("hello", "error", &detailedError{output: "boom"})
+	if got := buf.String(); !strings.Contains(got, "msg=hello") || !strings.Contains(got, "output=boom") {
+		t.Errorf("text record = %q", got)
+	}
+
+	if err := Setup(&buf, "xml", "info"); err == nil {
+		t.Error("Setup accepted an unknown format")
+	}
+	if err := Setup(&buf, FormatJSON, "loud"); err == nil {
+		t.Error("Setup accepted an unknown level")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{name: "debug", want: slog.LevelDebug},
+		{name: "INFO", want: slog.LevelInfo},
+		{name: " warn ", want: slog.LevelWarn},
+		{name: "error", want: slog.LevelError},
+		{name: "loud", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.name)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("ParseLevel(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package metrics holds the Prometheus metrics updated across the node.
+// Gauges describing resources are collected at scrape time in the api
+// package; this package only has the counters and state updated as events
+// happen.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Namespace prefixes every metric name
+const Namespace = "rayai"
+
+// Result label values
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Registry holds the node's metrics, along with the Go runtime and process
+// collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Heartbeats counts heartbeats sent to the manager by result
+	Heartbeats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "heartbeats_total",
+		Help:      "Heartbeats sent to the manager, by result.",
+	}, []string{"result"})
+
+	// HeartbeatLastSuccess is the time of the last accepted heartbeat
+	HeartbeatLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "heartbeat_last_success_timestamp_seconds",
+		Help:      "Unix time of the last heartbeat accepted by the manager.",
+	})
+
+	// Registrations counts registration attempts by result
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "registrations_total",
+		Help:      "Registration attempts with the manager, by result.",
+	}, []string{"result"})
+
+	// RegistrationLastSuccess is the time of the last successful registration
+	RegistrationLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "registration_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful registration with the manager.",
+	})
+
+	// RoleTransitions counts role reconciliation outcomes
+	RoleTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "role_transitions_total",
+		Help:      "Ray role transitions, by trigger source, target role and result.",
+	}, []string{"source", "role", "result"})
+
+	// RayUp is 1 while the local Ray runtime is running
+	RayUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "ray_up",
+		Help:      "Whether the local Ray runtime was running at the last check.",
+	})
+
+	// RequestDuration observes API request latencies
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Node API request latencies, by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Heartbeats,
+		HeartbeatLastSuccess,
+		Registrations,
+		RegistrationLastSuccess,
+		RoleTransitions,
+		RayUp,
+		RequestDuration,
+	)
+}
+
+// BoolValue converts a flag to a gauge value
+func BoolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// RecordHeartbeat counts a heartbeat attempt, failed if err is set
+func RecordHeartbeat(err error) {
+	record(Heartbeats, HeartbeatLastSuccess, err)
+}
+
+// RecordRegistration counts a registration attempt, failed if err is set
+func RecordRegistration(err error) {
+	record(Registrations, RegistrationLastSuccess, err)
+}
+
+// record counts an attempt by result, stamping lastSuccess on success
+func record(attempts *prometheus.CounterVec, lastSuccess prometheus.Gauge, err error) {
+	if err != nil {
+		attempts.WithLabelValues(ResultFailure).Inc()
+		return
+	}
+	attempts.WithLabelValues(ResultSuccess).Inc()
+	lastSuccess.SetToCurrentTime()
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireClientCert returns a gin middleware that rejects requests whose TLS
+// connection carries no client certificate verified by the server's config.
+// Servers only request client certificates, so routes that anyone may call,
+// such as health probes, are registered before it.
+func RequireClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Access denied. A client certificate is required to access this API.",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireClientCert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.Use(RequireClientCert())
+	router.GET("/status", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	tests := []struct {
+		name string
+		path string
+		tls  *tls.ConnectionState
+		want int
+	}{
+		{name: "verified certificate", path: "/status", tls: verified, want: http.StatusOK},
+		{name: "no certificate", path: "/status", tls: &tls.ConnectionState{}, want: http.StatusForbidden},
+		{name: "plain HTTP", path: "/status", want: http.StatusForbidden},
+		{name: "probe without certificate", path: "/healthz", tls: &tls.ConnectionState{}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.TLS = tt.tls
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -1,70 +1,210 @@
 package middleware
 
 import (
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 )
 
-// IPRestrictionMiddleware creates a middleware that restricts access to specific IPs
-func IPRestrictionMiddleware(allowedIPs []string) gin.HandlerFunc {
+// IPFilter restricts access to a set of allowed IPs/CIDRs. Forwarding headers
+// (X-Forwarded-For, X-Real-IP) are only honored when the direct peer is one
+// of the trusted proxies, so clients cannot spoof their address.
+type IPFilter struct {
+	allowAll bool
+	allowed  []*net.IPNet
+	trusted  []*net.IPNet
+}
+
+// NewIPFilter parses the allowlist and trusted proxy list. Entries may be
+// single IPv4/IPv6 addresses or CIDR ranges; "*" in the allowlist allows all.
+func NewIPFilter(allowedIPs, trustedProxies []string) (*IPFilter, error) {
+	f := &IPFilter{}
+
+	for _, entry := range allowedIPs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			f.allowAll = true
+			continue
+		}
+		ipNet, err := parseNet(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed IP %q: %w", entry, err)
+		}
+		f.allowed = append(f.allowed, ipNet)
+	}
+
+	for _, entry := range trustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		ipNet, err := parseNet(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		f.trusted = append(f.trusted, ipNet)
+	}
+
+	return f, nil
+}
+
+// Middleware returns a gin middleware that rejects requests from clients
+// outside the allowlist
+func (f *IPFilter) Middleware() gin.HandlerFunc {
+	return f.handle
+}
+
+// handle rejects the request unless its client is allowed
+func (f *IPFilter) handle(c *gin.Context) {
+	if !f.Allowed(f.ClientIP(c.Request)) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Access denied. Your IP is not allowed to access this API.",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// ReloadableIPFilter applies an IPFilter that can be replaced while
+// requests are being served
+type ReloadableIPFilter struct {
+	filter atomic.Pointer[IPFilter]
+}
+
+// NewReloadableIPFilter returns a reloadable filter starting with f
+func NewReloadableIPFilter(f *IPFilter) *ReloadableIPFilter {
+	r := &ReloadableIPFilter{}
+	r.filter.Store(f)
+	return r
+}
+
+// Set replaces the filter applied to subsequent requests
+func (r *ReloadableIPFilter) Set(f *IPFilter) {
+	r.filter.Store(f)
+}
+
+// Middleware returns a gin middleware applying the current filter
+func (r *ReloadableIPFilter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get client IP address
-		clientIP := getClientIP(c)
-
-		// Check if client IP is allowed
-		allowed := false
-		for _, ip := range allowedIPs {
-			if ip == clientIP || ip == "*" { // Allow all if "*" is in the list
-				allowed = true
+		r.filter.Load().handle(c)
+	}
+}
+
+// Allowed reports whether ip is covered by the allowlist
+func (f *IPFilter) Allowed(ip net.IP) bool {
+	if f.allowAll {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	return containsIP(f.allowed, ip)
+}
+
+// ClientIP extracts the client IP address from the request. The forwarding
+// headers are consulted only when the direct peer is a trusted proxy.
+func (f *IPFilter) ClientIP(r *http.Request) net.IP {
+	remote := parseIP(remoteHost(r.RemoteAddr))
+	if remote == nil || !containsIP(f.trusted, remote) {
+		return remote
+	}
+
+	// Walk X-Forwarded-For from the closest hop outwards, skipping trusted
+	// proxies; the first untrusted hop is the client
+	if hops := forwardedHops(r.Header.Values("X-Forwarded-For")); len(hops) > 0 {
+		client := remote
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := parseIP(hops[i])
+			if ip == nil {
+				// Malformed entry, don't trust anything beyond it
 				break
 			}
-
-			// Check for CIDR notation
-			if strings.Contains(ip, "/") {
-				_, ipNet, err := net.ParseCIDR(ip)
-				if err == nil {
-					clientIPAddr := net.ParseIP(clientIP)
-					if ipNet.Contains(clientIPAddr) {
-						allowed = true
-						break
-					}
-				}
+			client = ip
+			if !containsIP(f.trusted, ip) {
+				break
 			}
 		}
+		return client
+	}
+
+	if ip := parseIP(r.Header.Get("X-Real-IP")); ip != nil {
+		return ip
+	}
+
+	return remote
+}
 
-		if !allowed {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Access denied. Your IP is not allowed to access this API.",
-			})
-			c.Abort()
-			return
+// parseNet parses an IP or CIDR into a network
+func parseNet(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
 		}
+		return ipNet, nil
+	}
 
-		c.Next()
+	ip := parseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address or CIDR")
 	}
+	bits := 8 * len(ip)
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
 }
 
-// getClientIP extracts the client IP address from the request
-func getClientIP(c *gin.Context) string {
-	// Try X-Forwarded-For header first (for proxies)
-	ip := c.GetHeader("X-Forwarded-For")
-	if ip != "" {
-		// X-Forwarded-For can contain multiple IPs, take the first one
-		ips := strings.Split(ip, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
+// parseIP parses an IP address, dropping any IPv6 zone and normalizing
+// IPv4-mapped IPv6 addresses to their 4-byte form
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4
 	}
+	return ip
+}
 
-	// Try X-Real-IP header next
-	ip = c.GetHeader("X-Real-IP")
-	if ip != "" {
-		return ip
+// remoteHost strips the port from a request's RemoteAddr
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// forwardedHops flattens one or more X-Forwarded-For headers into a list of hops
+func forwardedHops(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
 	}
+	return hops
+}
 
-	// Otherwise, use the remote address
-	return c.ClientIP()
+// containsIP reports whether ip belongs to any of the networks
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "203.0.113.7:5000",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "forwarded for from untrusted peer is ignored",
+			remoteAddr: "203.0.113.7:5000",
+			xff:        []string{"10.0.0.5"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "real ip from untrusted peer is ignored",
+			remoteAddr: "203.0.113.7:5000",
+			xRealIP:    "10.0.0.5",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer with trusted proxies configured",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "203.0.113.7:5000",
+			xff:        []string{"10.0.0.5"},
+			xRealIP:    "10.0.0.6",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "forwarded for from trusted peer",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:5000",
+			xff:        []string{"198.51.100.9"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "real ip from trusted peer",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:5000",
+			xRealIP:    "198.51.100.9",
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "chain of trusted hops",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:5000",
+			xff:        []string{"198.51.100.9, 10.0.0.3", "10.0.0.2"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "spoofed leftmost hop is not trusted",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:5000",
+			xff:        []string{"127.0.0.1, 198.51.100.9, 10.0.0.2"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "all hops trusted",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:5000",
+			xff:        []string{"10.0.0.3, 10.0.0.2"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "malformed hop stops the walk",
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:5000",
+			xff:        []string{"198.51.100.9, not-an-ip, 10.0.0.2"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "malformed only hop falls back to peer",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:5000",
+			xff:        []string{"999.1.1.1"},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "malformed real ip falls back to peer",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "10.0.0.1:5000",
+			xRealIP:    "bogus",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "ipv6 peer with zone",
+			remoteAddr: "[fe80::1%eth0]:5000",
+			want:       "fe80::1",
+		},
+		{
+			name:       "trusted ipv6 peer with zone",
+			trusted:    []string{"fe80::/10"},
+			remoteAddr: "[fe80::1%eth0]:5000",
+			xff:        []string{"2001:db8::9"},
+			want:       "2001:db8::9",
+		},
+		{
+			name:       "ipv4 mapped peer matches ipv4 proxy",
+			trusted:    []string{"10.0.0.1"},
+			remoteAddr: "[::ffff:10.0.0.1]:5000",
+			xff:        []string{"::ffff:198.51.100.9"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "remote addr without port",
+			remoteAddr: "203.0.113.7",
+			want:       "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewIPFilter(nil, tt.trusted)
+			if err != nil {
+				t.Fatalf("NewIPFilter: %v", err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.xRealIP != "" {
+				r.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+
+			got := f.ClientIP(r)
+			if !got.Equal(net.ParseIP(tt.want)) {
+				t.Errorf("ClientIP = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIPFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		trusted []string
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "ips and cidrs", allowed: []string{"10.0.0.1", "192.168.0.0/16", "2001:db8::/32", " ::1 "}},
+		{name: "wildcard", allowed: []string{"*"}},
+		{name: "blank entries skipped", allowed: []string{"", "  "}, trusted: []string{""}},
+		{name: "bad allowed ip", allowed: []string{"10.0.0.300"}, wantErr: true},
+		{name: "bad allowed cidr", allowed: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "bad trusted proxy", trusted: []string{"proxy.local"}, wantErr: true},
+		{name: "wildcard is not a trusted proxy", trusted: []string{"*"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIPFilter(tt.allowed, tt.trusted)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewIPFilter error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		ip      string
+		want    bool
+	}{
+		{name: "exact ip", allowed: []string{"10.0.0.1"}, ip: "10.0.0.1", want: true},
+		{name: "outside list", allowed: []string{"10.0.0.1"}, ip: "10.0.0.2", want: false},
+		{name: "in cidr", allowed: []string{"192.168.0.0/16"}, ip: "192.168.4.20", want: true},
+		{name: "ipv6 cidr", allowed: []string{"2001:db8::/32"}, ip: "2001:db8::1", want: true},
+		{name: "ipv4 mapped address", allowed: []string{"10.0.0.1"}, ip: "::ffff:10.0.0.1", want: true},
+		{name: "wildcard", allowed: []string{"*"}, ip: "203.0.113.7", want: true},
+		{name: "wildcard allows unknown client", allowed: []string{"*"}, want: true},
+		{name: "unknown client", allowed: []string{"10.0.0.1"}, want: false},
+		{name: "empty list denies", ip: "10.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewIPFilter(tt.allowed, nil)
+			if err != nil {
+				t.Fatalf("NewIPFilter: %v", err)
+			}
+			if got := f.Allowed(parseIP(tt.ip)); got != tt.want {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRejectsSpoofedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	f, err := NewIPFilter([]string{"127.0.0.1"}, []string{"10.0.0.1"})
+	if err != nil {
+		t.Fatalf("NewIPFilter: %v", err)
+	}
+	router := gin.New()
+	router.Use(NewReloadableIPFilter(f).Middleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		value      string
+		want       int
+	}{
+		{name: "allowed peer", remoteAddr: "127.0.0.1:5000", want: http.StatusOK},
+		{name: "spoofed forwarded for", remoteAddr: "203.0.113.7:5000", header: "X-Forwarded-For", value: "127.0.0.1", want: http.StatusForbidden},
+		{name: "spoofed real ip", remoteAddr: "203.0.113.7:5000", header: "X-Real-IP", value: "127.0.0.1", want: http.StatusForbidden},
+		{name: "forwarded through trusted proxy", remoteAddr: "10.0.0.1:5000", header: "X-Forwarded-For", value: "127.0.0.1", want: http.StatusOK},
+		{name: "trusted proxy itself is not allowed", remoteAddr: "10.0.0.1:5000", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger logs every request to log, with the last error a handler attached,
+// at warn level for client errors and error level for server errors. Health
+// probes are logged at debug level.
+func Logger(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		case c.FullPath() == "/healthz" || c.FullPath() == "/readyz":
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if last := c.Errors.Last(); last != nil {
+			attrs = append(attrs, slog.Any("error", last.Err))
+		}
+		log.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// Recovery turns a panicking handler into a 500 response, logging the panic
+// and stack to log
+func Recovery(log *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		log.Error("Panic handling request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"error", fmt.Sprint(err),
+			"stack", string(debug.Stack()))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
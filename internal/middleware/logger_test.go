@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	router := gin.New()
+	router.Use(Logger(log))
+	router.GET("/status", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/start/worker", func(c *gin.Context) {
+		c.Error(errors.New("no head IP"))
+		c.Status(http.StatusBadRequest)
+	})
+	router.POST("/start/head", func(c *gin.Context) {
+		c.Error(errors.New("first"))
+		c.Error(errors.New("ray start failed"))
+		c.Status(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		method    string
+		path      string
+		wantLevel string
+		wantCode  int
+		wantError string
+	}{
+		{method: http.MethodGet, path: "/status", wantLevel: "INFO", wantCode: http.StatusOK},
+		{method: http.MethodGet, path: "/healthz", wantLevel: "DEBUG", wantCode: http.StatusOK},
+		{method: http.MethodPost, path: "/start/worker", wantLevel: "WARN", wantCode: http.StatusBadRequest, wantError: "no head IP"},
+		{method: http.MethodPost, path: "/start/head", wantLevel: "ERROR", wantCode: http.StatusInternalServerError, wantError: "ray start failed"},
+		{method: http.MethodGet, path: "/missing", wantLevel: "WARN", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(tt.method, tt.path+"?token=x", nil)
+			req.RemoteAddr = "10.0.0.5:40000"
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			var rec map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+				t.Fatalf("decoding %q: %v", buf.String(), err)
+			}
+			if rec["msg"] != "Request" || rec["level"] != tt.wantLevel {
+				t.Errorf("record = %v, want a %s Request record", rec, tt.wantLevel)
+			}
+			if rec["method"] != tt.method || rec["path"] != tt.path || rec["status"] != float64(tt.wantCode) || rec["client_ip"] != "10.0.0.5" {
+				t.Errorf("record = %v, want method, path without query, status and client IP", rec)
+			}
+			if _, ok := rec["latency"].(float64); !ok {
+				t.Errorf("record = %v, want a latency", rec)
+			}
+			if errMsg, ok := rec["error"]; (tt.wantError == "" && ok) || (tt.wantError != "" && errMsg != tt.wantError) {
+				t.Errorf("error = %v, want %q", errMsg, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	router := gin.New()
+	router.Use(Recovery(log))
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("decoding %q: %v", buf.String(), err)
+	}
+	stack, _ := rec["stack"].(string)
+	if rec["msg"] != "Panic handling request" || rec["error"] != "boom" || rec["path"] != "/panic" || !strings.Contains(stack, "goroutine") {
+		t.Errorf("record = %v", rec)
+	}
+}
@@ -0,0 +1,428 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA is a self-signed CA issuing certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+var serial int64
+
+func newSerial() *big.Int {
+	serial++
+	return big.NewInt(serial)
+}
+
+func newCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          newSerial(),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, valid for the IP
+// 127.0.0.1, the host localhost and as a client certificate
+func (ca *testCA) issue(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	return ca.issueFor(t, name, net.ParseIP("127.0.0.1"), "localhost")
+}
+
+// issueFor returns a PEM certificate and key for name, valid for the given
+// IP addresses and DNS names and as a client certificate
+func (ca *testCA) issueFor(t *testing.T, name string, ip net.IP, dnsNames ...string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: newSerial(),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{ip},
+		DNSNames:     dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to path and moves its modification time forward,
+// so a rewrite within the file system's timestamp resolution is noticed
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	var next time.Time
+	if info, err := os.Stat(path); err == nil {
+		next = info.ModTime().Add(time.Second)
+	} else {
+		next = time.Now()
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, next, next); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkAlways disables the throttle of file checks for the test
+func checkAlways(t *testing.T) {
+	old := checkInterval
+	checkInterval = 0
+	t.Cleanup(func() { checkInterval = old })
+}
+
+// startTLSServer serves handler (a no-op when nil) over TLS with cfg on a
+// loopback port and returns its address. httptest's TLS server is not used
+// since it installs a certificate of its own ahead of GetCertificate.
+func startTLSServer(t *testing.T, cfg *tls.Config, handler http.HandlerFunc) string {
+	t.Helper()
+	if handler == nil {
+		handler = func(http.ResponseWriter, *http.Request) {}
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: handler, ErrorLog: log.New(io.Discard, "", 0)}
+	go server.Serve(ln)
+	t.Cleanup(func() { server.Close() })
+	return ln.Addr().String()
+}
+
+// servedCommonName dials addr and returns the subject of the server's certificate
+func servedCommonName(t *testing.T, addr string) string {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestServerConfigReloadsCertificate(t *testing.T) {
+	checkAlways(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	ca := newCA(t, "ca")
+	certPEM, keyPEM := ca.issue(t, "first")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	cfg, err := ServerConfig(certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	addr := startTLSServer(t, cfg, nil)
+
+	if name := servedCommonName(t, addr); name != "first" {
+		t.Fatalf("served %q, want first", name)
+	}
+
+	certPEM, keyPEM = ca.issue(t, "second")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	if name := servedCommonName(t, addr); name != "second" {
+		t.Errorf("served %q after rotation, want second", name)
+	}
+
+	// A broken rotation keeps the previous certificate
+	writeFile(t, certFile, []byte("not a certificate"))
+	if name := servedCommonName(t, addr); name != "second" {
+		t.Errorf("served %q after a failed reload, want second", name)
+	}
+}
+
+func TestServerConfigVerifiesClientCertificate(t *testing.T) {
+	checkAlways(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	clientCAFile := filepath.Join(dir, "clients.pem")
+
+	serverCA, clientCA := newCA(t, "server-ca"), newCA(t, "client-ca")
+	certPEM, keyPEM := serverCA.issue(t, "server")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, clientCAFile, clientCA.pem)
+
+	cfg, err := ServerConfig(certFile, keyFile, clientCAFile)
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	url := "https://" + startTLSServer(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.VerifiedChains) == 0 {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+
+	get := func(clientCert []byte, clientKey []byte) error {
+		pool := x509.NewCertPool()
+		pool.AddCert(serverCA.cert)
+		tlsCfg := &tls.Config{RootCAs: pool}
+		if clientCert != nil {
+			pair, err := tls.X509KeyPair(clientCert, clientKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tlsCfg.Certificates = []tls.Certificate{pair}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsCfg}}
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("no verified client certificate: %s", resp.Status)
+		}
+		return nil
+	}
+
+	// The handshake succeeds without a certificate so health probes can
+	// connect, but the request carries no verified chain
+	if err := get(nil, nil); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("request without a client certificate = %v, want it unverified", err)
+	}
+	if err := get(clientCA.issue(t, "client")); err != nil {
+		t.Errorf("request with a client certificate: %v", err)
+	}
+	if err := get(serverCA.issue(t, "client")); err == nil {
+		t.Error("request with a certificate of another CA succeeded")
+	}
+
+	// Clients of a rotated-in CA are accepted without a restart
+	newClientCA := newCA(t, "client-ca-2")
+	writeFile(t, clientCAFile, newClientCA.pem)
+	if err := get(newClientCA.issue(t, "client")); err != nil {
+		t.Errorf("request with a certificate of the rotated CA: %v", err)
+	}
+}
+
+func TestClientTLSReloadsCA(t *testing.T) {
+	checkAlways(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "manager-ca.pem")
+
+	firstCA, secondCA := newCA(t, "first-ca"), newCA(t, "second-ca")
+	writeFile(t, caFile, firstCA.pem)
+
+	// The manager's certificate, switched to one of secondCA below
+	var current tls.Certificate
+	serverCert := func(ca *testCA) {
+		pair, err := tls.X509KeyPair(ca.issue(t, "manager"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		current = pair
+	}
+	serverCert(firstCA)
+
+	url := "https://" + startTLSServer(t, &tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return &current, nil
+	}}, nil)
+
+	clientTLS, err := NewClientTLS(caFile, "", "")
+	if err != nil {
+		t.Fatalf("NewClientTLS: %v", err)
+	}
+	get := func() error {
+		// A new transport per request so every call makes a new handshake
+		client := &http.Client{Transport: clientTLS.Transport()}
+		resp, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(); err != nil {
+		t.Fatalf("request trusted by the CA: %v", err)
+	}
+
+	serverCert(secondCA)
+	if err := get(); err == nil {
+		t.Fatal("request to a server of an untrusted CA succeeded")
+	}
+
+	writeFile(t, caFile, secondCA.pem)
+	if err := get(); err != nil {
+		t.Errorf("request after rotating the CA bundle: %v", err)
+	}
+}
+
+func TestClientTLSVerifiesServerName(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "manager-ca.pem")
+	ca := newCA(t, "ca")
+	writeFile(t, caFile, ca.pem)
+
+	// Valid for 127.0.0.1 and localhost
+	pair, err := tls.X509KeyPair(ca.issue(t, "manager"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTLSServer(t, &tls.Config{Certificates: []tls.Certificate{pair}}, nil)
+	_, port, _ := net.SplitHostPort(addr)
+
+	clientTLS, err := NewClientTLS(caFile, "", "")
+	if err != nil {
+		t.Fatalf("NewClientTLS: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		serverName string
+		wantErr    bool
+	}{
+		{name: "matching IP", serverName: "127.0.0.1"},
+		{name: "matching DNS name", serverName: "localhost"},
+		{name: "other DNS name", serverName: "manager.example.com", wantErr: true},
+		{name: "other IP", serverName: "10.9.9.9", wantErr: true},
+		{name: "no name", serverName: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", addr, clientTLS.Config(tt.serverName))
+			if err == nil {
+				conn.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The transport verifies the host of each dial, including IPs the
+	// handshake state leaves unnamed
+	get := func(host string) error {
+		client := &http.Client{Transport: clientTLS.Transport()}
+		resp, err := client.Get("https://" + net.JoinHostPort(host, port))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if err := get("127.0.0.1"); err != nil {
+		t.Errorf("request to a matching IP: %v", err)
+	}
+
+	// A certificate issued only for another IP is refused
+	otherPair, err := tls.X509KeyPair(ca.issueFor(t, "peer", net.ParseIP("10.9.9.9")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherAddr := startTLSServer(t, &tls.Config{Certificates: []tls.Certificate{otherPair}}, nil)
+	client := &http.Client{Transport: clientTLS.Transport()}
+	if resp, err := client.Get("https://" + otherAddr); err == nil {
+		resp.Body.Close()
+		t.Error("request to 127.0.0.1 accepted a certificate for 10.9.9.9")
+	}
+}
+
+func TestClientTLSPresentsClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	ca := newCA(t, "ca")
+	certPEM, keyPEM := ca.issue(t, "node")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	serverPair, err := tls.X509KeyPair(ca.issue(t, "manager"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	var seen string
+	addr := startTLSServer(t, &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}, func(w http.ResponseWriter, r *http.Request) {
+		seen = r.TLS.PeerCertificates[0].Subject.CommonName
+	})
+
+	clientTLS, err := NewClientTLS("", certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewClientTLS: %v", err)
+	}
+	cfg := clientTLS.Config("")
+	cfg.RootCAs = pool
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	resp, err := client.Get("https://" + addr)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if seen != "node" {
+		t.Errorf("server saw client certificate %q, want node", seen)
+	}
+}
+
+func TestLoadCAPool(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	writeFile(t, empty, []byte("no certificates here"))
+
+	if _, err := LoadCAPool(empty); err == nil {
+		t.Error("LoadCAPool accepted a file without certificates")
+	}
+	if _, err := LoadCAPool(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("LoadCAPool accepted a missing file")
+	}
+}
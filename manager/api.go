@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/bandwidth"
+)
+
+// Registration is the body of POST /api/register. The resource fields hold
+// the resource package's types, which depend on this package.
+type Registration struct {
+	NodeID    string `json:"node_id,omitempty"` // Previous ID the node asks to keep
+	Hostname  string `json:"hostname"`
+	PublicKey string `json:"public_key"`
+	Resources any    `json:"resources"`
+	Geo       any    `json:"geo,omitempty"`
+	Bandwidth any    `json:"bandwidth,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// RegistrationResult is the response to a registration
+type RegistrationResult struct {
+	NodeID string `json:"node_id"` // Empty if the manager assigned none
+	Status string `json:"status"`
+}
+
+// Deregistration is the body of POST /api/deregister
+type Deregistration struct {
+	NodeID    string `json:"node_id"`
+	Hostname  string `json:"hostname"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// RoleAssignment is the response of GET /api/node/role
+type RoleAssignment struct {
+	Role    string          `json:"role"`
+	HeadIP  string          `json:"head_ip,omitempty"`
+	Options json.RawMessage `json:"options,omitempty"` // Ray start options, decoded by the ray package
+}
+
+// PeerReport is the body of POST /api/node/bandwidth
+type PeerReport struct {
+	NodeID    string             `json:"node_id"`
+	Timestamp int64              `json:"timestamp"`
+	Results   []bandwidth.Result `json:"results"`
+}
+
+// Register registers the node, returning the ID the manager assigned
+func (c *Client) Register(ctx context.Context, reg *Registration) (*RegistrationResult, error) {
+	var result RegistrationResult
+	err := c.do(ctx, call{
+		op:     "registration",
+		method: http.MethodPost,
+		path:   "/api/register",
+		body:   reg,
+		out:    &result,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Deregister tells the manager the node is leaving
+func (c *Client) Deregister(ctx context.Context, dereg *Deregistration) error {
+	return c.do(ctx, call{
+		op:     "deregistration",
+		method: http.MethodPost,
+		path:   "/api/deregister",
+		body:   dereg,
+	})
+}
+
+// Heartbeat posts a heartbeat in the given payload schema version. A 404
+// or 410 means the manager forgot the node and matches ErrNodeRejected.
+func (c *Client) Heartbeat(ctx context.Context, schemaVersion int, heartbeat any) error {
+	return c.do(ctx, call{
+		op:       "heartbeat",
+		method:   http.MethodPost,
+		path:     "/api/heartbeat",
+		header:   http.Header{"X-Heartbeat-Schema": {strconv.Itoa(schemaVersion)}},
+		body:     heartbeat,
+		rejected: []int{http.StatusNotFound, http.StatusGone},
+	})
+}
+
+// Role asks which role the node should run
+func (c *Client) Role(ctx context.Context, nodeID string) (*RoleAssignment, error) {
+	var role RoleAssignment
+	err := c.do(ctx, call{
+		op:     "role request",
+		method: http.MethodGet,
+		path:   "/api/node/role",
+		query:  nodeQuery(nodeID),
+		out:    &role,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Peers returns the peers the node should measure. A manager without peer
+// assignment answers 404, which means none.
+func (c *Client) Peers(ctx context.Context, nodeID string) ([]string, error) {
+	var result struct {
+		Peers []string `json:"peers"`
+	}
+	err := c.do(ctx, call{
+		op:     "peer request",
+		method: http.MethodGet,
+		path:   "/api/node/peers",
+		query:  nodeQuery(nodeID),
+		out:    &result,
+	})
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result.Peers, nil
+}
+
+// ReportPeerBandwidth sends peer measurement results
+func (c *Client) ReportPeerBandwidth(ctx context.Context, report *PeerReport) error {
+	return c.do(ctx, call{
+		op:     "bandwidth report",
+		method: http.MethodPost,
+		path:   "/api/node/bandwidth",
+		body:   report,
+	})
+}
+
+// nodeQuery identifies the node in a query string, if it has an ID yet
+func nodeQuery(nodeID string) url.Values {
+	if nodeID == "" {
+		return nil
+	}
+	return url.Values{"node_id": {nodeID}}
+}
@@ -0,0 +1,210 @@
+// Package manager is the node's client for the subnet manager API. Calls
+// are retried with exponential backoff while failures are transient, and
+// errors are classified so callers can tell a manager that is down from one
+// that refuses the node.
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// DefaultTimeout bounds a single attempt of a call
+const DefaultTimeout = 10 * time.Second
+
+// Backoff configures the retries of transient failures
+type Backoff struct {
+	Attempts int           // Total attempts per call, including the first
+	Initial  time.Duration // Delay before the first retry, doubled for each further one
+	Max      time.Duration // Upper bound of a delay before jitter
+	Jitter   float64       // Random spread of each delay, as a fraction
+}
+
+// DefaultBackoff retries a call three times within about four seconds
+var DefaultBackoff = Backoff{
+	Attempts: 4,
+	Initial:  500 * time.Millisecond,
+	Max:      5 * time.Second,
+	Jitter:   0.2,
+}
+
+// Delay returns how long to wait before the given retry, counted from 1
+func (b Backoff) Delay(retry int) time.Duration {
+	delay := b.Initial
+	for i := 1; i < retry && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	if spread := int64(float64(delay) * b.Jitter); spread > 0 {
+		delay += time.Duration(rand.Int63n(2*spread+1) - spread)
+	}
+	return delay
+}
+
+// Client calls the manager configured in the live configuration, so a
+// reloaded MANAGER_IP applies to the next call
+type Client struct {
+	config  *config.Store
+	http    *http.Client
+	backoff Backoff
+	timeout time.Duration
+
+	mu         sync.Mutex
+	rejectedFn func()
+}
+
+// NewClient creates a client sending requests through httpClient, which
+// is expected to sign them with the node key
+func NewClient(store *config.Store, httpClient *http.Client) *Client {
+	return &Client{
+		config:  store,
+		http:    httpClient,
+		backoff: DefaultBackoff,
+		timeout: DefaultTimeout,
+	}
+}
+
+// SetBackoff replaces the retry policy
+func (c *Client) SetBackoff(b Backoff) {
+	c.backoff = b
+}
+
+// SetTimeout replaces the bound of a single attempt
+func (c *Client) SetTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+// SetRejectedFunc registers a callback run whenever the manager rejects the
+// node's identity, so the node can register again
+func (c *Client) SetRejectedFunc(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rejectedFn = fn
+}
+
+// Configured reports whether a manager address is set
+func (c *Client) Configured() bool {
+	return c.config.Load().ManagerIP != ""
+}
+
+// URL returns the base URL of the manager, empty if none is configured
+func (c *Client) URL() string {
+	return c.config.Load().ManagerURL()
+}
+
+// call describes one manager API request
+type call struct {
+	op     string // Operation named in errors, e.g. "heartbeat"
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any // Sent as JSON when non-nil
+	out    any // Decoded from the response when non-nil
+
+	// rejected lists statuses besides 401 meaning the node ID is unknown
+	rejected []int
+}
+
+// do performs cl, retrying transient failures until the attempts are used
+// up or ctx is done
+func (c *Client) do(ctx context.Context, cl call) error {
+	base := c.URL()
+	if base == "" {
+		return ErrNotConfigured
+	}
+	target := base + cl.path
+	if len(cl.query) > 0 {
+		target += "?" + cl.query.Encode()
+	}
+
+	var body []byte
+	if cl.body != nil {
+		var err error
+		if body, err = json.Marshal(cl.body); err != nil {
+			return fmt.Errorf("failed to marshal %s request: %w", cl.op, err)
+		}
+	}
+
+	attempts := c.backoff.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, cl, target, body)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrNodeRejected) {
+			c.mu.Lock()
+			rejectedFn := c.rejectedFn
+			c.mu.Unlock()
+			if rejectedFn != nil {
+				rejectedFn()
+			}
+		}
+		if attempt >= attempts || !Retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.backoff.Delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends a single request of cl to target
+func (c *Client) attempt(ctx context.Context, cl call, target string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, cl.method, target, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", cl.op, err)
+	}
+	for key, values := range cl.header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return &NetworkError{Op: cl.op, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Drain a little so the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		return newStatusError(cl.op, resp, cl.rejected)
+	}
+	if cl.out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(cl.out); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", cl.op, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package manager_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager/managertest"
+	"github.com/unicornultrafoundation/subnet-rayai-node/signing"
+)
+
+// fastBackoff retries immediately so tests don't sleep
+var fastBackoff = manager.Backoff{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond}
+
+// newClient returns a client of srv signing requests with key, or sending
+// them unsigned if key is nil
+func newClient(t *testing.T, srv *managertest.Server, key ed25519.PrivateKey) *manager.Client {
+	t.Helper()
+	httpClient := &http.Client{}
+	if key != nil {
+		httpClient.Transport = signing.NewTransport(http.DefaultTransport, key)
+	}
+	c := manager.NewClient(config.NewStore(&config.Config{ManagerIP: srv.URL}), httpClient)
+	c.SetBackoff(fastBackoff)
+	return c
+}
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// register registers a node with key and returns its ID
+func register(t *testing.T, c *manager.Client, key ed25519.PrivateKey) string {
+	t.Helper()
+	reg := &manager.Registration{Hostname: "test", Timestamp: time.Now().Unix()}
+	if key != nil {
+		reg.PublicKey = signing.EncodePublicKey(key.Public().(ed25519.PublicKey))
+	}
+	result, err := c.Register(context.Background(), reg)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return result.NodeID
+}
+
+func heartbeat(c *manager.Client, nodeID string) error {
+	return c.Heartbeat(context.Background(), 1, map[string]string{"node_id": nodeID})
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := manager.Backoff{Initial: 100 * time.Millisecond, Max: time.Second}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := b.Delay(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("Delay(1) with jitter = %v, want within 50%% of 100ms", d)
+		}
+	}
+}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int
+		wantStatus   int // 0 for success
+	}{
+		{name: "success", wantRequests: 1},
+		{name: "recovers", statuses: []int{503, 500}, wantRequests: 3},
+		{name: "throttled", statuses: []int{429}, wantRequests: 2},
+		{name: "gives up", statuses: []int{502, 503, 504, 503}, wantRequests: 3, wantStatus: 504},
+		{name: "bad request not retried", statuses: []int{400}, wantRequests: 1, wantStatus: 400},
+		{name: "forbidden not retried", statuses: []int{403}, wantRequests: 1, wantStatus: 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := managertest.NewServer()
+			defer srv.Close()
+			c := newClient(t, srv, nil)
+			nodeID := register(t, c, nil)
+
+			srv.Fail("/api/heartbeat", tt.statuses...)
+			err := heartbeat(c, nodeID)
+
+			if got := srv.Requests("/api/heartbeat"); got != tt.wantRequests {
+				t.Errorf("heartbeat requests = %d, want %d", got, tt.wantRequests)
+			}
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Errorf("Heartbeat: %v", err)
+				}
+				return
+			}
+			var statusErr *manager.StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus {
+				t.Fatalf("Heartbeat error = %v, want status %d", err, tt.wantStatus)
+			}
+			if manager.Retryable(err) != (tt.wantStatus >= 500) {
+				t.Errorf("Retryable(%v) = %v", err, manager.Retryable(err))
+			}
+			if errors.Is(err, manager.ErrNodeRejected) {
+				t.Errorf("status %d matches ErrNodeRejected", tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestClientRetryStopsWithContext(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	c := newClient(t, srv, nil)
+	c.SetBackoff(manager.Backoff{Attempts: 5, Initial: time.Hour, Max: time.Hour})
+	srv.Fail("/api/node/role", 503)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Role(ctx, "node-1")
+	if !manager.Retryable(err) || time.Since(start) > 5*time.Second {
+		t.Errorf("Role = %v after %v, want the 503 once the context is done", err, time.Since(start))
+	}
+	if got := srv.Requests("/api/node/role"); got != 1 {
+		t.Errorf("role requests = %d, want 1", got)
+	}
+}
+
+func TestClientNetworkError(t *testing.T) {
+	srv := managertest.NewServer()
+	c := newClient(t, srv, nil)
+	srv.Close()
+
+	_, err := c.Role(context.Background(), "")
+	var netErr *manager.NetworkError
+	if !errors.As(err, &netErr) || !manager.Retryable(err) {
+		t.Errorf("Role against a closed server = %v, want a retryable NetworkError", err)
+	}
+}
+
+func TestClientNotConfigured(t *testing.T) {
+	c := manager.NewClient(config.NewStore(&config.Config{}), http.DefaultClient)
+	if c.Configured() {
+		t.Error("Configured without MANAGER_IP")
+	}
+	if err := heartbeat(c, "node-1"); !errors.Is(err, manager.ErrNotConfigured) {
+		t.Errorf("Heartbeat = %v, want ErrNotConfigured", err)
+	}
+}
+
+func TestClientRejection(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(srv *managertest.Server, nodeID string)
+	}{
+		{name: "forgotten node answered 404", prepare: func(srv *managertest.Server, nodeID string) { srv.Forget(nodeID) }},
+		{name: "401", prepare: func(srv *managertest.Server, _ string) { srv.Fail("/api/heartbeat", 401) }},
+		{name: "410", prepare: func(srv *managertest.Server, _ string) { srv.Fail("/api/heartbeat", 410) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := managertest.NewServer()
+			defer srv.Close()
+			c := newClient(t, srv, nil)
+			rejections := 0
+			c.SetRejectedFunc(func() { rejections++ })
+			nodeID := register(t, c, nil)
+
+			tt.prepare(srv, nodeID)
+			err := heartbeat(c, nodeID)
+			if !errors.Is(err, manager.ErrNodeRejected) || manager.Retryable(err) {
+				t.Fatalf("Heartbeat = %v, want a final ErrNodeRejected", err)
+			}
+			if rejections != 1 || srv.Requests("/api/heartbeat") != 1 {
+				t.Errorf("rejected callback ran %d times after %d requests, want 1 and 1",
+					rejections, srv.Requests("/api/heartbeat"))
+			}
+		})
+	}
+}
+
+func TestClientPeersNotFound(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	c := newClient(t, srv, nil)
+
+	srv.SetPeers("10.0.0.5", "10.0.0.6:3333")
+	peers, err := c.Peers(context.Background(), "node-1")
+	if err != nil || len(peers) != 2 {
+		t.Errorf("Peers = %v, %v; want both peers", peers, err)
+	}
+
+	// A manager without peer assignment has no such endpoint
+	srv.Fail("/api/node/peers", 404)
+	peers, err = c.Peers(context.Background(), "node-1")
+	if err != nil || peers != nil {
+		t.Errorf("Peers on 404 = %v, %v; want none", peers, err)
+	}
+}
+
+func TestClientSignatures(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	srv.RequireSignatures()
+
+	// Unsigned requests are refused like an unknown node
+	unsigned := newClient(t, srv, nil)
+	_, err := unsigned.Register(context.Background(), &manager.Registration{Hostname: "test"})
+	if !errors.Is(err, manager.ErrNodeRejected) {
+		t.Fatalf("unsigned Register = %v, want ErrNodeRejected", err)
+	}
+
+	key := newKey(t)
+	signed := newClient(t, srv, key)
+	nodeID := register(t, signed, key)
+	if err := heartbeat(signed, nodeID); err != nil {
+		t.Fatalf("signed Heartbeat: %v", err)
+	}
+	if got := len(srv.Heartbeats()); got != 1 {
+		t.Errorf("manager accepted %d heartbeats, want 1", got)
+	}
+
+	// A key the manager never registered is refused
+	other := newClient(t, srv, newKey(t))
+	if err := heartbeat(other, nodeID); !errors.Is(err, manager.ErrNodeRejected) {
+		t.Errorf("Heartbeat with an unregistered key = %v, want ErrNodeRejected", err)
+	}
+	if got := len(srv.Heartbeats()); got != 1 {
+		t.Errorf("manager accepted %d heartbeats, want 1", got)
+	}
+}
+
+func TestClientFollowsReloadedManager(t *testing.T) {
+	first, second := managertest.NewServer(), managertest.NewServer()
+	defer first.Close()
+	defer second.Close()
+
+	store := config.NewStore(&config.Config{ManagerIP: first.URL})
+	c := manager.NewClient(store, &http.Client{})
+	if _, err := c.Role(context.Background(), ""); err != nil {
+		t.Fatalf("Role: %v", err)
+	}
+
+	store.Set(&config.Config{ManagerIP: second.URL})
+	if _, err := c.Role(context.Background(), ""); err != nil {
+		t.Fatalf("Role: %v", err)
+	}
+	if first.Requests("/api/node/role") != 1 || second.Requests("/api/node/role") != 1 {
+		t.Errorf("role requests = %d and %d, want one per manager",
+			first.Requests("/api/node/role"), second.Requests("/api/node/role"))
+	}
+}
@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotConfigured is returned for calls while no manager address is set
+	ErrNotConfigured = errors.New("manager IP not configured")
+	// ErrNodeRejected matches errors of calls the manager refused because
+	// it does not recognize the node, which must register again
+	ErrNodeRejected = errors.New("node rejected by manager")
+)
+
+// StatusError is returned when the manager answers with a status other
+// than 200 OK
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Status     string
+	rejected   bool
+}
+
+// newStatusError classifies resp, treating 401 and the rejected statuses
+// as a rejection of the node
+func newStatusError(op string, resp *http.Response, rejected []int) *StatusError {
+	err := &StatusError{
+		Op:         op,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		rejected:   resp.StatusCode == http.StatusUnauthorized,
+	}
+	for _, code := range rejected {
+		if resp.StatusCode == code {
+			err.rejected = true
+		}
+	}
+	return err
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s failed with status: %s", e.Op, e.Status)
+}
+
+// Is makes rejections match ErrNodeRejected
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNodeRejected && e.rejected
+}
+
+// NetworkError is returned when the manager could not be reached or did
+// not answer in time
+type NetworkError struct {
+	Op  string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s: failed to reach manager: %v", e.Op, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether err is transient and the call may succeed if
+// repeated: the manager was unreachable, timed out, throttled the node or
+// failed with a server error. Rejections and other client errors are fatal.
+func Retryable(err error) bool {
+	var netErr *NetworkError
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch code := statusErr.StatusCode; {
+		case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+			return true
+		case code >= http.StatusInternalServerError:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,288 @@
+// Package managertest provides an in-process fake of the subnet manager for
+// exercising the node's manager client, heartbeats and role handling.
+package managertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
+	"github.com/unicornultrafoundation/subnet-rayai-node/signing"
+)
+
+// Server is a fake manager listening on a local httptest server. Nodes
+// register and get sequential IDs; heartbeats from unknown nodes are
+// answered with 404 like a manager that forgot them. Failures queued with
+// Fail take precedence over the simulation.
+type Server struct {
+	*httptest.Server
+
+	mu               sync.Mutex
+	verifier         *signing.Verifier // nil accepts unsigned requests
+	nextID           int
+	nodes            map[string]string // Node ID to encoded public key
+	role             manager.RoleAssignment
+	peers            []string
+	failures         map[string][]int // Path to queued statuses
+	requests         map[string]int   // Path to number of requests
+	registrations    []manager.Registration
+	heartbeats       []json.RawMessage
+	deregistrations  []manager.Deregistration
+	bandwidthReports []manager.PeerReport
+}
+
+// NewServer starts a fake manager assigning the head role. Callers must
+// Close it.
+func NewServer() *Server {
+	s := &Server{
+		nodes:    make(map[string]string),
+		role:     manager.RoleAssignment{Role: "head"},
+		failures: make(map[string][]int),
+		requests: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/register", s.register)
+	mux.HandleFunc("/api/deregister", s.deregister)
+	mux.HandleFunc("/api/heartbeat", s.heartbeat)
+	mux.HandleFunc("/api/node/role", s.getRole)
+	mux.HandleFunc("/api/node/peers", s.getPeers)
+	mux.HandleFunc("/api/node/bandwidth", s.reportBandwidth)
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// RequireSignatures makes the server answer 401 to requests that are not
+// signed with the key the node registered
+func (s *Server) RequireSignatures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verifier = signing.NewVerifier(0)
+}
+
+// Fail queues statuses answered to the next requests for path, such as
+// "/api/heartbeat", one per request
+func (s *Server) Fail(path string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], statuses...)
+}
+
+// SetRole sets the role assigned to every node
+func (s *Server) SetRole(role manager.RoleAssignment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.role = role
+}
+
+// SetPeers sets the peers every node is asked to measure
+func (s *Server) SetPeers(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers = peers
+}
+
+// Forget drops a registered node, so its next heartbeat is rejected
+func (s *Server) Forget(nodeID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.nodes, nodeID)
+}
+
+// Requests returns the number of requests received for path, including
+// failed ones
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// Registrations returns the registrations accepted so far
+func (s *Server) Registrations() []manager.Registration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]manager.Registration(nil), s.registrations...)
+}
+
+// Heartbeats returns the bodies of the heartbeats accepted so far
+func (s *Server) Heartbeats() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.heartbeats...)
+}
+
+// Deregistrations returns the deregistrations accepted so far
+func (s *Server) Deregistrations() []manager.Deregistration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]manager.Deregistration(nil), s.deregistrations...)
+}
+
+// BandwidthReports returns the peer bandwidth reports accepted so far
+func (s *Server) BandwidthReports() []manager.PeerReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]manager.PeerReport(nil), s.bandwidthReports...)
+}
+
+// intercept counts requests and answers queued failures and requests
+// failing signature verification before they reach next
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		status := 0
+		if queue := s.failures[r.URL.Path]; len(queue) > 0 {
+			status = queue[0]
+			s.failures[r.URL.Path] = queue[1:]
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		if err := s.verify(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verify checks the request signature if signatures are required. Until a
+// node has registered, the key it presents is trusted.
+func (s *Server) verify(r *http.Request) error {
+	s.mu.Lock()
+	verifier := s.verifier
+	s.mu.Unlock()
+	if verifier == nil {
+		return nil
+	}
+
+	pub, err := signing.PublicKey(r)
+	if err != nil {
+		return err
+	}
+	if err := verifier.Verify(r, pub); err != nil {
+		return err
+	}
+
+	encoded := signing.EncodePublicKey(pub)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.nodes {
+		if key == encoded {
+			return nil
+		}
+	}
+	if r.URL.Path == "/api/register" {
+		return nil
+	}
+	return fmt.Errorf("unknown node key")
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var reg manager.Registration
+	if !decode(w, r, &reg) {
+		return
+	}
+
+	s.mu.Lock()
+	nodeID := reg.NodeID
+	if _, known := s.nodes[nodeID]; !known {
+		s.nextID++
+		nodeID = fmt.Sprintf("node-%d", s.nextID)
+	}
+	s.nodes[nodeID] = reg.PublicKey
+	s.registrations = append(s.registrations, reg)
+	s.mu.Unlock()
+
+	respond(w, manager.RegistrationResult{NodeID: nodeID, Status: "registered"})
+}
+
+func (s *Server) deregister(w http.ResponseWriter, r *http.Request) {
+	var dereg manager.Deregistration
+	if !decode(w, r, &dereg) {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.nodes, dereg.NodeID)
+	s.deregistrations = append(s.deregistrations, dereg)
+	s.mu.Unlock()
+
+	respond(w, map[string]string{"status": "deregistered"})
+}
+
+func (s *Server) heartbeat(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if !decode(w, r, &body) {
+		return
+	}
+	var hb struct {
+		NodeID string `json:"node_id"`
+	}
+	if err := json.Unmarshal(body, &hb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	_, known := s.nodes[hb.NodeID]
+	if known {
+		s.heartbeats = append(s.heartbeats, body)
+	}
+	s.mu.Unlock()
+
+	if !known {
+		http.Error(w, "unknown node", http.StatusNotFound)
+		return
+	}
+	respond(w, map[string]string{"status": "ok"})
+}
+
+func (s *Server) getRole(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	role := s.role
+	s.mu.Unlock()
+	respond(w, role)
+}
+
+func (s *Server) getPeers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	peers := s.peers
+	s.mu.Unlock()
+	respond(w, map[string][]string{"peers": peers})
+}
+
+func (s *Server) reportBandwidth(w http.ResponseWriter, r *http.Request) {
+	var report manager.PeerReport
+	if !decode(w, r, &report) {
+		return
+	}
+
+	s.mu.Lock()
+	s.bandwidthReports = append(s.bandwidthReports, report)
+	s.mu.Unlock()
+
+	respond(w, map[string]string{"status": "ok"})
+}
+
+// decode reads the JSON request body into v, answering 400 if it is invalid
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// respond writes v as a JSON response
+func respond(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
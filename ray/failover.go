@@ -0,0 +1,95 @@
+package ray
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
+)
+
+// desiredRole returns the role to converge to and the source of the
+// decision. While the manager can't be reached the node keeps its current
+// state for the grace period, then applies the role failure policy.
+func (s *Service) desiredRole(ctx context.Context) (*RoleInfo, string, error) {
+	role, err := s.GetRole(ctx)
+	if err == nil {
+		s.recordAssignment(role)
+		return role, SourceManager, nil
+	}
+	if !managerUnavailable(err) {
+		return nil, "", err
+	}
+
+	cfg := s.config.Load()
+	down := s.markManagerDown()
+	if wait := cfg.RoleFailureGrace - time.Since(down); wait > 0 {
+		return nil, "", fmt.Errorf("%w; keeping the current state for up to %s", err, wait.Round(time.Second))
+	}
+
+	var fallback *RoleInfo
+	switch cfg.RoleFailurePolicy {
+	case config.RoleFailureIdle:
+		fallback = &RoleInfo{Role: RoleNone}
+	case config.RoleFailureDefault:
+		fallback = &RoleInfo{Role: NodeRole(cfg.RoleFailureDefault)}
+	default:
+		if fallback = s.LastAssigned(); fallback == nil {
+			return nil, "", fmt.Errorf("%w; no role was received before to keep", err)
+		}
+	}
+	s.log.Warn("Manager unreachable, applying role failure policy",
+		"policy", cfg.RoleFailurePolicy, "since", down, "fallback_role", fallback.Role, "error", err)
+	return fallback, SourceFailover, nil
+}
+
+// managerUnavailable reports whether err means the manager could not be
+// asked for a role: it was unreachable, timed out, throttled the node or
+// failed with a server error. A manager refusing the node or the request,
+// or sending an invalid role, is answering and never triggers failover.
+func managerUnavailable(err error) bool {
+	return manager.Retryable(err) && !errors.Is(err, manager.ErrNodeRejected)
+}
+
+// markManagerDown records a failed role request and returns when the
+// manager was first found unreachable
+func (s *Service) markManagerDown() time.Time {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.managerDown.IsZero() {
+		s.managerDown = time.Now()
+	}
+	return s.managerDown
+}
+
+// recordAssignment remembers a role received from the manager, persisting
+// it when it changed
+func (s *Service) recordAssignment(role *RoleInfo) {
+	s.stateMu.Lock()
+	s.managerDown = time.Time{}
+	changed := s.lastAssigned == nil || !s.lastAssigned.Equal(*role)
+	if changed {
+		assigned := *role
+		s.lastAssigned = &assigned
+		s.assignedAt = time.Now()
+	}
+	s.stateMu.Unlock()
+
+	if changed {
+		s.saveRoles()
+	}
+}
+
+// LastAssigned returns the last role received from the manager, including
+// before a restart, or nil if none was
+func (s *Service) LastAssigned() *RoleInfo {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.lastAssigned == nil {
+		return nil
+	}
+	assigned := *s.lastAssigned
+	return &assigned
+}
@@ -0,0 +1,168 @@
+package ray
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager/managertest"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray/raytest"
+)
+
+// newFailoverService returns a service asking srv for its role, failing
+// each role request after a single attempt
+func newFailoverService(t *testing.T, srv *managertest.Server, fake *raytest.FakeRunner, mutate ...func(*config.Config)) *Service {
+	t.Helper()
+	s := newTestService(t, fake, append([]func(*config.Config){func(c *config.Config) {
+		c.ManagerIP = srv.URL
+		c.RoleFailurePolicy = config.RoleFailureKeep
+		c.RoleFailureDefault = string(RoleHead)
+		c.RoleFailureGrace = time.Hour
+	}}, mutate...)...)
+	s.manager.SetBackoff(manager.Backoff{Attempts: 1})
+	return s
+}
+
+// expireGrace pretends the manager has been unreachable for longer than
+// any grace period
+func expireGrace(s *Service) {
+	s.stateMu.Lock()
+	s.managerDown = time.Now().Add(-24 * time.Hour)
+	s.stateMu.Unlock()
+}
+
+func TestFailoverAfterGrace(t *testing.T) {
+	worker := RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.1"}
+	tests := []struct {
+		policy      string
+		wantResult  string
+		wantApplied NodeRole
+	}{
+		{policy: config.RoleFailureKeep, wantResult: ResultUnchanged, wantApplied: RoleWorker},
+		{policy: config.RoleFailureIdle, wantResult: ResultStopped, wantApplied: RoleNone},
+		{policy: config.RoleFailureDefault, wantResult: ResultRestarted, wantApplied: RoleHead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			srv := managertest.NewServer()
+			defer srv.Close()
+			srv.SetRole(manager.RoleAssignment{Role: string(worker.Role), HeadIP: worker.HeadIP})
+			fake := raytest.NewFakeRunner()
+			s := newFailoverService(t, srv, fake, func(c *config.Config) { c.RoleFailurePolicy = tt.policy })
+
+			if result, err := s.SetupNodeByRole(context.Background()); err != nil || result != ResultStarted {
+				t.Fatalf("SetupNodeByRole = %q, %v, want started", result, err)
+			}
+			calls := len(fake.Calls())
+
+			// Within the grace period the node keeps its state
+			srv.Fail("/api/node/role", 503, 502)
+			_, err := s.SetupNodeByRole(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "keeping the current state") {
+				t.Fatalf("SetupNodeByRole during grace = %v, want the state kept", err)
+			}
+			if len(fake.Calls()) != calls {
+				t.Errorf("Ray commands run during grace: %v", fake.Calls()[calls:])
+			}
+
+			// Afterwards the policy applies
+			expireGrace(s)
+			result, err := s.SetupNodeByRole(context.Background())
+			if err != nil || result != tt.wantResult {
+				t.Fatalf("SetupNodeByRole after grace = %q, %v, want %s", result, err, tt.wantResult)
+			}
+			if applied := s.RoleState().Applied; applied == nil || applied.Role != tt.wantApplied {
+				t.Errorf("applied role = %v, want %s", applied, tt.wantApplied)
+			}
+			if last := s.LastAssigned(); last == nil || !last.Equal(worker) {
+				t.Errorf("LastAssigned = %v, want the manager's worker role", last)
+			}
+			if tt.wantResult != ResultUnchanged {
+				if history := s.History(); history[len(history)-1].Source != SourceFailover {
+					t.Errorf("last transition = %+v, want source %s", history[len(history)-1], SourceFailover)
+				}
+			}
+
+			// The manager's answer takes over again once it is back
+			if result, err := s.SetupNodeByRole(context.Background()); err != nil {
+				t.Fatalf("SetupNodeByRole after recovery = %q, %v", result, err)
+			}
+			if applied := s.RoleState().Applied; applied == nil || !applied.Equal(worker) {
+				t.Errorf("applied role after recovery = %v, want %v", applied, worker)
+			}
+		})
+	}
+}
+
+func TestNoFailoverWhenManagerAnswers(t *testing.T) {
+	for _, status := range []int{400, 401, 403, 404} {
+		srv := managertest.NewServer()
+		fake := raytest.NewFakeRunner()
+		s := newFailoverService(t, srv, fake, func(c *config.Config) {
+			c.RoleFailurePolicy = config.RoleFailureIdle
+			c.RoleFailureGrace = 0
+		})
+		if _, err := s.SetupNodeByRole(context.Background()); err != nil {
+			t.Fatalf("SetupNodeByRole: %v", err)
+		}
+		calls := len(fake.Calls())
+
+		srv.Fail("/api/node/role", status)
+		_, err := s.SetupNodeByRole(context.Background())
+		if err == nil || strings.Contains(err.Error(), "keeping the current state") {
+			t.Errorf("status %d: SetupNodeByRole = %v, want the error without failover", status, err)
+		}
+		if len(fake.Calls()) != calls || !fake.Running() {
+			t.Errorf("status %d: Ray commands run: %v", status, fake.Calls()[calls:])
+		}
+		s.stateMu.Lock()
+		down := s.managerDown
+		s.stateMu.Unlock()
+		if !down.IsZero() {
+			t.Errorf("status %d marked the manager as down", status)
+		}
+		srv.Close()
+	}
+
+	// An invalid role is an answer too
+	srv := managertest.NewServer()
+	defer srv.Close()
+	srv.SetRole(manager.RoleAssignment{Role: string(RoleWorker)})
+	s := newFailoverService(t, srv, raytest.NewFakeRunner(), func(c *config.Config) { c.RoleFailureGrace = 0 })
+	if _, err := s.SetupNodeByRole(context.Background()); err == nil || !strings.Contains(err.Error(), "no head IP") {
+		t.Errorf("SetupNodeByRole with a worker role without head = %v, want it rejected", err)
+	}
+}
+
+func TestFailoverKeepsRestoredRoleAcrossRestart(t *testing.T) {
+	srv := managertest.NewServer()
+	worker := RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.1"}
+	srv.SetRole(manager.RoleAssignment{Role: string(worker.Role), HeadIP: worker.HeadIP})
+	fake := raytest.NewFakeRunner()
+	s := newFailoverService(t, srv, fake, func(c *config.Config) { c.RoleFailureGrace = 0 })
+	if _, err := s.SetupNodeByRole(context.Background()); err != nil {
+		t.Fatalf("SetupNodeByRole: %v", err)
+	}
+
+	// The daemon restarts while the manager is gone and Ray keeps running
+	srv.Close()
+	restarted := NewServiceWithRunner(s.config, nil, fake)
+	restarted.manager.SetBackoff(manager.Backoff{Attempts: 1})
+	if last := restarted.LastAssigned(); last == nil || !last.Equal(worker) {
+		t.Fatalf("LastAssigned after restart = %v, want %v", last, worker)
+	}
+
+	result, err := restarted.SetupNodeByRole(context.Background())
+	if err != nil || result != ResultUnchanged {
+		t.Fatalf("SetupNodeByRole after restart = %q, %v, want unchanged", result, err)
+	}
+	if applied := restarted.RoleState().Applied; applied == nil || !applied.Equal(worker) {
+		t.Errorf("applied role = %v, want %v", applied, worker)
+	}
+	if subs := fake.Subcommands(); len(startCalls(fake)) != 1 || hasArg(subs, "stop") {
+		t.Errorf("commands = %v, want Ray left running", subs)
+	}
+}
@@ -0,0 +1,206 @@
+package ray
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// RayStartOptions are the parameters passed to `ray start`. Zero values
+// (nil for the CPU/GPU counts) leave Ray's own defaults in place.
+type RayStartOptions struct {
+	// Port is the GCS port of the head node: the port a head listens on,
+	// or the port a worker connects to
+	Port                int                `json:"port,omitempty"`
+	DashboardHost       string             `json:"dashboard_host,omitempty"`
+	DashboardPort       int                `json:"dashboard_port,omitempty"`
+	RayClientServerPort int                `json:"ray_client_server_port,omitempty"`
+	NumCPUs             *int               `json:"num_cpus,omitempty"`
+	NumGPUs             *int               `json:"num_gpus,omitempty"`
+	Memory              int64              `json:"memory,omitempty"`              // Bytes
+	ObjectStoreMemory   int64              `json:"object_store_memory,omitempty"` // Bytes
+	Resources           map[string]float64 `json:"resources,omitempty"`           // Custom resources
+	TempDir             string             `json:"temp_dir,omitempty"`
+}
+
+// OptionsFromConfig returns the operator-configured start options
+func OptionsFromConfig(cfg *config.Config) RayStartOptions {
+	opts := RayStartOptions{
+		Port:                cfg.RayHeadPort,
+		DashboardHost:       cfg.RayDashboardHost,
+		DashboardPort:       cfg.RayDashboardPort,
+		RayClientServerPort: cfg.RayClientServerPort,
+		NumCPUs:             cfg.RayNumCPUs,
+		NumGPUs:             cfg.RayNumGPUs,
+		Memory:              cfg.RayMemory,
+		ObjectStoreMemory:   cfg.RayObjectStoreMemory,
+		Resources:           cfg.RayResources,
+		TempDir:             cfg.RayTempDir,
+	}
+	if opts.Port == 0 {
+		opts.Port = DefaultPort
+	}
+	return opts
+}
+
+// WithDetectedGPUs returns o completed with the GPUs detected on the node.
+// Ray only auto-detects NVIDIA GPUs, so --num-gpus defaults to the detected
+// count, and a "<vendor>_gpu" custom resource (e.g. "amd_gpu") lets tasks ask
+// for a vendor. Values configured by the operator take precedence.
+func (o RayStartOptions) WithDetectedGPUs(vendor string, count int) RayStartOptions {
+	if vendor == "" || count <= 0 {
+		return o
+	}
+
+	if o.NumGPUs == nil {
+		o.NumGPUs = &count
+	}
+
+	name := vendor + "_gpu"
+	if _, ok := o.Resources[name]; !ok {
+		resources := make(map[string]float64, len(o.Resources)+1)
+		for k, v := range o.Resources {
+			resources[k] = v
+		}
+		resources[name] = float64(count)
+		o.Resources = resources
+	}
+	return o
+}
+
+// Merge returns o overlaid with the fields set in override, typically the
+// options sent by the manager with a role assignment. Resource amounts are
+// capped at the values configured in o, so the manager can never make a
+// node contribute more than its operator allows. The temp dir and dashboard
+// host are local concerns and are never overridden.
+func (o RayStartOptions) Merge(override *RayStartOptions) RayStartOptions {
+	if override == nil {
+		return o
+	}
+
+	merged := o
+	if override.Port != 0 {
+		merged.Port = override.Port
+	}
+	if override.DashboardPort != 0 {
+		merged.DashboardPort = override.DashboardPort
+	}
+	if override.RayClientServerPort != 0 {
+		merged.RayClientServerPort = override.RayClientServerPort
+	}
+	merged.NumCPUs = capCount(o.NumCPUs, override.NumCPUs)
+	merged.NumGPUs = capCount(o.NumGPUs, override.NumGPUs)
+	merged.Memory = capBytes(o.Memory, override.Memory)
+	merged.ObjectStoreMemory = capBytes(o.ObjectStoreMemory, override.ObjectStoreMemory)
+
+	if len(override.Resources) > 0 {
+		merged.Resources = make(map[string]float64, len(o.Resources)+len(override.Resources))
+		for name, amount := range o.Resources {
+			merged.Resources[name] = amount
+		}
+		for name, amount := range override.Resources {
+			if limit, ok := o.Resources[name]; ok && limit < amount {
+				amount = limit
+			}
+			merged.Resources[name] = amount
+		}
+	}
+
+	return merged
+}
+
+// HeadArgs returns the `ray start` arguments for a head node
+func (o RayStartOptions) HeadArgs() []string {
+	port := o.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	host := o.DashboardHost
+	if host == "" {
+		host = "0.0.0.0"
+	}
+
+	args := []string{
+		"start",
+		"--head",
+		fmt.Sprintf("--port=%d", port),
+		fmt.Sprintf("--dashboard-host=%s", host),
+	}
+	if o.DashboardPort != 0 {
+		args = append(args, fmt.Sprintf("--dashboard-port=%d", o.DashboardPort))
+	}
+	if o.RayClientServerPort != 0 {
+		args = append(args, fmt.Sprintf("--ray-client-server-port=%d", o.RayClientServerPort))
+	}
+	// Ray only honors --temp-dir on the head node; workers inherit it
+	if o.TempDir != "" {
+		args = append(args, fmt.Sprintf("--temp-dir=%s", o.TempDir))
+	}
+
+	return append(args, o.resourceArgs()...)
+}
+
+// WorkerArgs returns the `ray start` arguments for a worker node joining
+// the head at headIP
+func (o RayStartOptions) WorkerArgs(headIP string) []string {
+	port := o.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	args := []string{
+		"start",
+		"--address", net.JoinHostPort(headIP, strconv.Itoa(port)),
+	}
+
+	return append(args, o.resourceArgs()...)
+}
+
+// resourceArgs returns the arguments limiting what the node contributes
+func (o RayStartOptions) resourceArgs() []string {
+	var args []string
+	if o.NumCPUs != nil {
+		args = append(args, fmt.Sprintf("--num-cpus=%d", *o.NumCPUs))
+	}
+	if o.NumGPUs != nil {
+		args = append(args, fmt.Sprintf("--num-gpus=%d", *o.NumGPUs))
+	}
+	if o.Memory > 0 {
+		args = append(args, fmt.Sprintf("--memory=%d", o.Memory))
+	}
+	if o.ObjectStoreMemory > 0 {
+		args = append(args, fmt.Sprintf("--object-store-memory=%d", o.ObjectStoreMemory))
+	}
+	if len(o.Resources) > 0 {
+		// json.Marshal sorts map keys, keeping the arguments deterministic
+		if data, err := json.Marshal(o.Resources); err == nil {
+			args = append(args, fmt.Sprintf("--resources=%s", data))
+		}
+	}
+	return args
+}
+
+// capCount returns the smaller of two optional counts
+func capCount(limit, value *int) *int {
+	if value == nil {
+		return limit
+	}
+	if limit != nil && *limit < *value {
+		return limit
+	}
+	return value
+}
+
+// capBytes returns the smaller of two byte amounts where zero means unset
+func capBytes(limit, value int64) int64 {
+	if value <= 0 {
+		return limit
+	}
+	if limit > 0 && limit < value {
+		return limit
+	}
+	return value
+}
@@ -0,0 +1,234 @@
+package ray
+
+import (
+	"reflect"
+	"testing"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     RayStartOptions
+		override *RayStartOptions
+		want     RayStartOptions
+	}{
+		{
+			name: "nil override",
+			base: RayStartOptions{Port: 6379, NumCPUs: intPtr(4)},
+			want: RayStartOptions{Port: 6379, NumCPUs: intPtr(4)},
+		},
+		{
+			name:     "ports replace configured ones",
+			base:     RayStartOptions{Port: 6379, DashboardPort: 8265},
+			override: &RayStartOptions{Port: 6380, DashboardPort: 8266, RayClientServerPort: 10001},
+			want:     RayStartOptions{Port: 6380, DashboardPort: 8266, RayClientServerPort: 10001},
+		},
+		{
+			name:     "local settings are never overridden",
+			base:     RayStartOptions{DashboardHost: "127.0.0.1", TempDir: "/data/ray"},
+			override: &RayStartOptions{DashboardHost: "0.0.0.0", TempDir: "/tmp"},
+			want:     RayStartOptions{DashboardHost: "127.0.0.1", TempDir: "/data/ray"},
+		},
+		{
+			name:     "counts below the limit",
+			base:     RayStartOptions{NumCPUs: intPtr(8), NumGPUs: intPtr(2)},
+			override: &RayStartOptions{NumCPUs: intPtr(4), NumGPUs: intPtr(1)},
+			want:     RayStartOptions{NumCPUs: intPtr(4), NumGPUs: intPtr(1)},
+		},
+		{
+			name:     "counts capped at the limit",
+			base:     RayStartOptions{NumCPUs: intPtr(8), NumGPUs: intPtr(2)},
+			override: &RayStartOptions{NumCPUs: intPtr(64), NumGPUs: intPtr(8)},
+			want:     RayStartOptions{NumCPUs: intPtr(8), NumGPUs: intPtr(2)},
+		},
+		{
+			name:     "zero count is honored",
+			base:     RayStartOptions{NumGPUs: intPtr(2)},
+			override: &RayStartOptions{NumGPUs: intPtr(0)},
+			want:     RayStartOptions{NumGPUs: intPtr(0)},
+		},
+		{
+			name:     "counts without a limit",
+			override: &RayStartOptions{NumCPUs: intPtr(16)},
+			want:     RayStartOptions{NumCPUs: intPtr(16)},
+		},
+		{
+			name:     "unset count keeps the limit",
+			base:     RayStartOptions{NumCPUs: intPtr(8)},
+			override: &RayStartOptions{Port: 6380},
+			want:     RayStartOptions{Port: 6380, NumCPUs: intPtr(8)},
+		},
+		{
+			name:     "bytes below the limit",
+			base:     RayStartOptions{Memory: 8 << 30, ObjectStoreMemory: 4 << 30},
+			override: &RayStartOptions{Memory: 2 << 30, ObjectStoreMemory: 1 << 30},
+			want:     RayStartOptions{Memory: 2 << 30, ObjectStoreMemory: 1 << 30},
+		},
+		{
+			name:     "bytes capped at the limit",
+			base:     RayStartOptions{Memory: 8 << 30, ObjectStoreMemory: 4 << 30},
+			override: &RayStartOptions{Memory: 64 << 30, ObjectStoreMemory: 32 << 30},
+			want:     RayStartOptions{Memory: 8 << 30, ObjectStoreMemory: 4 << 30},
+		},
+		{
+			name:     "bytes without a limit",
+			override: &RayStartOptions{Memory: 2 << 30},
+			want:     RayStartOptions{Memory: 2 << 30},
+		},
+		{
+			name:     "negative bytes keep the limit",
+			base:     RayStartOptions{Memory: 8 << 30},
+			override: &RayStartOptions{Memory: -1},
+			want:     RayStartOptions{Memory: 8 << 30},
+		},
+		{
+			name:     "resources merged and capped",
+			base:     RayStartOptions{Resources: map[string]float64{"ssd": 1, "nvidia_gpu": 2}},
+			override: &RayStartOptions{Resources: map[string]float64{"ssd": 4, "nvidia_gpu": 1, "team_a": 1}},
+			want:     RayStartOptions{Resources: map[string]float64{"ssd": 1, "nvidia_gpu": 1, "team_a": 1}},
+		},
+		{
+			name:     "no resource override keeps configured ones",
+			base:     RayStartOptions{Resources: map[string]float64{"ssd": 1}},
+			override: &RayStartOptions{},
+			want:     RayStartOptions{Resources: map[string]float64{"ssd": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.base.Merge(tt.override)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDoesNotModifyBase(t *testing.T) {
+	base := RayStartOptions{Resources: map[string]float64{"ssd": 1}}
+	base.Merge(&RayStartOptions{Resources: map[string]float64{"team_a": 1}})
+	if len(base.Resources) != 1 {
+		t.Errorf("base resources modified: %v", base.Resources)
+	}
+}
+
+func TestHeadArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RayStartOptions
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"start", "--head", "--port=6379", "--dashboard-host=0.0.0.0"},
+		},
+		{
+			name: "all options",
+			opts: RayStartOptions{
+				Port:                6380,
+				DashboardHost:       "127.0.0.1",
+				DashboardPort:       8265,
+				RayClientServerPort: 10001,
+				NumCPUs:             intPtr(4),
+				NumGPUs:             intPtr(0),
+				Memory:              1 << 30,
+				ObjectStoreMemory:   512 << 20,
+				Resources:           map[string]float64{"ssd": 1, "amd_gpu": 2},
+				TempDir:             "/data/ray",
+			},
+			want: []string{
+				"start", "--head", "--port=6380", "--dashboard-host=127.0.0.1",
+				"--dashboard-port=8265", "--ray-client-server-port=10001", "--temp-dir=/data/ray",
+				"--num-cpus=4", "--num-gpus=0", "--memory=1073741824", "--object-store-memory=536870912",
+				`--resources={"amd_gpu":2,"ssd":1}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.HeadArgs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HeadArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkerArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   RayStartOptions
+		headIP string
+		want   []string
+	}{
+		{
+			name:   "defaults",
+			headIP: "10.0.0.1",
+			want:   []string{"start", "--address", "10.0.0.1:6379"},
+		},
+		{
+			name:   "ipv6 head",
+			opts:   RayStartOptions{Port: 6380},
+			headIP: "2001:db8::1",
+			want:   []string{"start", "--address", "[2001:db8::1]:6380"},
+		},
+		{
+			name: "head-only options are dropped",
+			opts: RayStartOptions{
+				DashboardHost: "0.0.0.0",
+				DashboardPort: 8265,
+				TempDir:       "/data/ray",
+				NumCPUs:       intPtr(2),
+			},
+			headIP: "10.0.0.1",
+			want:   []string{"start", "--address", "10.0.0.1:6379", "--num-cpus=2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.WorkerArgs(tt.headIP); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WorkerArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDetectedGPUs(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   RayStartOptions
+		vendor string
+		count  int
+		want   RayStartOptions
+	}{
+		{
+			name: "no gpus",
+			want: RayStartOptions{},
+		},
+		{
+			name:   "detected gpus",
+			vendor: "amd",
+			count:  2,
+			want:   RayStartOptions{NumGPUs: intPtr(2), Resources: map[string]float64{"amd_gpu": 2}},
+		},
+		{
+			name:   "configured values win",
+			opts:   RayStartOptions{NumGPUs: intPtr(1), Resources: map[string]float64{"amd_gpu": 1}},
+			vendor: "amd",
+			count:  4,
+			want:   RayStartOptions{NumGPUs: intPtr(1), Resources: map[string]float64{"amd_gpu": 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.WithDetectedGPUs(tt.vendor, tt.count); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithDetectedGPUs = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+#!/bin/sh
+# Fake `ray` CLI for integration testing rayai-node without a Ray install.
+#
+# Usage: RAY_BIN_PATH=/path/to/fake-ray.sh rayai-node
+#
+# Environment:
+#   FAKE_RAY_STATE  directory holding the simulated runtime state (default /tmp/fake-ray)
+#   FAKE_RAY_FAIL   space-separated subcommands that should fail, e.g. "start stop"
+#   FAKE_RAY_DELAY  seconds to sleep before every command, to exercise timeouts
+
+SCRIPT_DIR=$(dirname "$0")
+STATE_DIR="${FAKE_RAY_STATE:-/tmp/fake-ray}"
+RUNNING="$STATE_DIR/running"
+mkdir -p "$STATE_DIR"
+echo "$*" >> "$STATE_DIR/calls.log"
+
+if [ -n "$FAKE_RAY_DELAY" ]; then
+  sleep "$FAKE_RAY_DELAY"
+fi
+
+for cmd in $FAKE_RAY_FAIL; do
+  if [ "$cmd" = "$1" ]; then
+    echo "fake-ray: $1 failed" >&2
+    exit 1
+  fi
+done
+
+case "$1" in
+  start)
+    if [ -f "$RUNNING" ]; then
+      echo "ConnectionError: Ray is already running" >&2
+      exit 1
+    fi
+    echo "$*" > "$RUNNING"
+    echo "Ray runtime started."
+    ;;
+  stop)
+    rm -f "$RUNNING"
+    echo "Stopped all 1 Ray processes."
+    ;;
+  status)
+    if [ ! -f "$RUNNING" ]; then
+      echo "Ray cluster is not found at 127.0.0.1:6379"
+      exit 1
+    fi
+    cat "$SCRIPT_DIR/status.txt"
+    ;;
+  *)
+    echo "fake-ray: unsupported command: $1" >&2
+    exit 2
+    ;;
+esac
@@ -0,0 +1,162 @@
+// Package raytest provides fakes for exercising ray.Service without a Ray
+// installation.
+package raytest
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatusOutput is the `ray status` output reported by the fakes while Ray
+// is running. fake-ray.sh prints the same file.
+//
+//go:embed status.txt
+var StatusOutput string
+
+// notRunningOutput mimics `ray status` when no cluster is reachable
+const notRunningOutput = "Ray cluster is not found at 127.0.0.1:6379\n"
+
+// ErrExit is returned for commands that exit with a non-zero status
+var ErrExit = errors.New("exit status 1")
+
+// Call records a command executed through a FakeRunner
+type Call struct {
+	Name string
+	Args []string
+}
+
+// Response is a scripted result for a command
+type Response struct {
+	Output string
+	Err    error
+	// Delay blocks the command for this long, or until its context is
+	// done, before responding. Use it to exercise command timeouts.
+	Delay time.Duration
+}
+
+// FakeRunner is a ray.Runner that simulates the Ray CLI in memory. By
+// default `start` brings the fake runtime up, `stop` brings it down and
+// `status` reports StatusOutput while running. Scripted responses queued
+// with On take precedence over the simulation.
+type FakeRunner struct {
+	mu       sync.Mutex
+	running  bool
+	scripted map[string][]Response
+	calls    []Call
+}
+
+// NewFakeRunner creates a FakeRunner with Ray not running
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{scripted: make(map[string][]Response)}
+}
+
+// On queues responses for a subcommand such as "start", "stop" or "status".
+// Each call consumes one response; once exhausted the simulation resumes.
+func (f *FakeRunner) On(subcommand string, responses ...Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripted[subcommand] = append(f.scripted[subcommand], responses...)
+}
+
+// SetRunning sets the simulated Ray runtime state
+func (f *FakeRunner) SetRunning(running bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = running
+}
+
+// Running reports the simulated Ray runtime state
+func (f *FakeRunner) Running() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.running
+}
+
+// Calls returns the commands executed so far
+func (f *FakeRunner) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Subcommands returns the first argument of each command executed so far,
+// e.g. ["status", "start", "status"]
+func (f *FakeRunner) Subcommands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	subcommands := make([]string, 0, len(f.calls))
+	for _, call := range f.calls {
+		subcommands = append(subcommands, subcommand(call))
+	}
+	return subcommands
+}
+
+// Run implements ray.Runner
+func (f *FakeRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	call := Call{Name: name, Args: append([]string(nil), args...)}
+	sub := subcommand(call)
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	var resp *Response
+	if queue := f.scripted[sub]; len(queue) > 0 {
+		resp = &queue[0]
+		f.scripted[sub] = queue[1:]
+	}
+	f.mu.Unlock()
+
+	if resp != nil {
+		if resp.Delay > 0 {
+			select {
+			case <-time.After(resp.Delay):
+			case <-ctx.Done():
+				return []byte(resp.Output), ctx.Err()
+			}
+		}
+		return []byte(resp.Output), resp.Err
+	}
+
+	return f.simulate(name, sub)
+}
+
+// simulate applies the default in-memory behaviour of the Ray CLI
+func (f *FakeRunner) simulate(name, sub string) ([]byte, error) {
+	if name == "rm" {
+		return nil, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch sub {
+	case "start":
+		if f.running {
+			return []byte("ConnectionError: Ray is already running\n"), ErrExit
+		}
+		f.running = true
+		return []byte("Ray runtime started.\n"), nil
+	case "stop":
+		f.running = false
+		return []byte("Stopped all 1 Ray processes.\n"), nil
+	case "status":
+		if !f.running {
+			return []byte(notRunningOutput), ErrExit
+		}
+		return []byte(StatusOutput), nil
+	default:
+		return nil, nil
+	}
+}
+
+// subcommand returns the Ray subcommand of a call, or the command name
+// for non-Ray commands
+func subcommand(call Call) string {
+	if len(call.Args) == 0 || strings.HasPrefix(call.Args[0], "-") {
+		return call.Name
+	}
+	return call.Args[0]
+}
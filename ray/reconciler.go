@@ -0,0 +1,284 @@
+package ray
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/metrics"
+	"github.com/unicornultrafoundation/subnet-rayai-node/resource"
+)
+
+const (
+	// historySize is the number of transitions kept for the API
+	historySize = 50
+	// minBackoff and maxBackoff bound the delay between failed attempts
+	minBackoff = 30 * time.Second
+	maxBackoff = 30 * time.Minute
+)
+
+// Reconciliation results
+const (
+	ResultUnchanged = "unchanged" // Runtime already matches the desired role
+	ResultIdle      = "idle"      // No role assigned and Ray not running
+	ResultStarted   = "started"   // Ray was started from a stopped state
+	ResultRestarted = "restarted" // Ray was stopped and started with a new role
+	ResultStopped   = "stopped"   // Ray was stopped because no role is assigned
+)
+
+// Sources of a role transition
+const (
+	SourceManager  = "manager"  // Role assignment received from the manager
+	SourceFailover = "failover" // Role failure policy while the manager is unreachable
+	SourceAPI      = "api"      // Manual start/stop through the node API
+	SourceShutdown = "shutdown" // Ray stopped while the daemon exits
+)
+
+// ErrBackoff is returned when a reconciliation is skipped because a
+// previous attempt failed recently
+var ErrBackoff = errors.New("backing off after failed role setup")
+
+// Transition records one attempt to change the local Ray runtime
+type Transition struct {
+	From   *RoleInfo `json:"from"` // nil when the previous state was unknown
+	To     RoleInfo  `json:"to"`
+	Source string    `json:"source"`
+	Result string    `json:"result,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// RoleState describes the outcome of role reconciliation so far
+type RoleState struct {
+	Applied             *RoleInfo  `json:"applied"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	NextAttempt         *time.Time `json:"next_attempt,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastAssigned        *RoleInfo  `json:"last_assigned,omitempty"`      // Last role received from the manager
+	ManagerDownSince    *time.Time `json:"manager_down_since,omitempty"` // Set while role requests fail
+}
+
+// Reconcile converges the local Ray runtime to the desired role. It is a
+// no-op when the runtime already matches; when the role or head address
+// changed Ray is stopped, its data cleared and restarted with the new role.
+// After a failure further attempts at the same role are delayed with
+// exponential backoff; a different role is attempted at once. Commands
+// still running when ctx is done are aborted.
+func (s *Service) Reconcile(ctx context.Context, desired RoleInfo, source string) (string, error) {
+	if err := s.lock(ctx); err != nil {
+		return "", err
+	}
+	defer s.unlock()
+
+	running := s.isRunning(ctx)
+
+	s.stateMu.Lock()
+	applied := s.applied
+	// The backoff only holds back retries of the role that failed
+	if s.failedRole != nil && !s.failedRole.Equal(desired) {
+		s.resetBackoff()
+	}
+	failures, nextAttempt := s.failures, s.nextAttempt
+	// After a restart the runtime may still be running the role saved
+	// before it; only the first reconciliation may rely on that
+	if applied == nil && running {
+		applied = s.restored
+	}
+	s.restored = nil
+	s.stateMu.Unlock()
+
+	if desired.Role == RoleNone && !running {
+		s.setApplied(&desired)
+		return ResultIdle, nil
+	}
+	if running && applied != nil && applied.Equal(desired) {
+		s.setApplied(&desired)
+		return ResultUnchanged, nil
+	}
+
+	if time.Now().Before(nextAttempt) {
+		return "", fmt.Errorf("%w (%d failures), next attempt at %s",
+			ErrBackoff, failures, nextAttempt.Format(time.RFC3339))
+	}
+
+	result, err := s.converge(ctx, desired, running)
+	s.finishTransition(desired, source, result, err)
+	if err != nil {
+		s.stateMu.Lock()
+		s.failures++
+		s.nextAttempt = time.Now().Add(backoffDelay(s.failures))
+		s.failedRole = &desired
+		s.lastError = err.Error()
+		s.stateMu.Unlock()
+		return "", err
+	}
+
+	return result, nil
+}
+
+// converge stops Ray if it is running and starts it with the desired role
+func (s *Service) converge(ctx context.Context, desired RoleInfo, running bool) (string, error) {
+	if running {
+		s.log.Info("Stopping Ray to apply role", "new_role", desired.Role)
+		if err := s.stopNode(ctx); err != nil {
+			return "", fmt.Errorf("failed to stop Ray: %w", err)
+		}
+
+		// Clear data
+		if err := s.ClearRayData(ctx); err != nil {
+			s.log.Warn("Failed to clear Ray data", "error", err)
+			// Continue despite errors
+		}
+	}
+
+	opts := s.baseOptions().Merge(desired.Options)
+
+	var err error
+	switch desired.Role {
+	case RoleNone:
+		return ResultStopped, nil
+	case RoleHead:
+		_, err = s.startHead(ctx, opts)
+	case RoleWorker:
+		_, err = s.startWorker(ctx, desired.HeadIP, opts)
+	default:
+		err = fmt.Errorf("unknown role: %s", desired.Role)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if running {
+		return ResultRestarted, nil
+	}
+	return ResultStarted, nil
+}
+
+// finishTransition records an attempt to move the runtime to the given role
+// and updates the applied role: to on success, unknown on failure
+func (s *Service) finishTransition(to RoleInfo, source, result string, err error) {
+	s.recordTransition(to, source, result, err)
+	s.saveRoles()
+}
+
+// recordTransition implements finishTransition without persisting the result
+func (s *Service) recordTransition(to RoleInfo, source, result string, err error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	t := Transition{
+		From:   s.applied,
+		To:     to,
+		Source: source,
+		At:     time.Now(),
+	}
+	if err != nil {
+		t.Error = err.Error()
+		metrics.RoleTransitions.WithLabelValues(source, string(to.Role), metrics.ResultFailure).Inc()
+	} else {
+		t.Result = result
+		metrics.RoleTransitions.WithLabelValues(source, string(to.Role), result).Inc()
+	}
+
+	s.history = append(s.history, t)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+
+	if err != nil {
+		s.applied = nil
+		logging.SetRole("")
+		return
+	}
+	s.applied = &to
+	logging.SetRole(string(to.Role))
+	s.resetBackoff()
+}
+
+// setApplied marks role as applied without recording a transition
+func (s *Service) setApplied(role *RoleInfo) {
+	s.stateMu.Lock()
+	changed := (s.applied == nil) != (role == nil) ||
+		(role != nil && !s.applied.Equal(*role))
+	s.applied = role
+	if role != nil {
+		logging.SetRole(string(role.Role))
+	}
+	s.resetBackoff()
+	s.stateMu.Unlock()
+
+	if changed {
+		s.saveRoles()
+	}
+}
+
+// resetBackoff clears the failure counters. Callers must hold stateMu.
+func (s *Service) resetBackoff() {
+	s.failures = 0
+	s.nextAttempt = time.Time{}
+	s.failedRole = nil
+	s.lastError = ""
+}
+
+// backoffDelay returns the delay after the given number of consecutive failures
+func backoffDelay(failures int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// RoleState returns the currently applied role and backoff state
+func (s *Service) RoleState() RoleState {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	state := RoleState{
+		ConsecutiveFailures: s.failures,
+		LastError:           s.lastError,
+	}
+	if s.applied != nil {
+		applied := *s.applied
+		state.Applied = &applied
+	}
+	if !s.nextAttempt.IsZero() {
+		next := s.nextAttempt
+		state.NextAttempt = &next
+	}
+	if s.lastAssigned != nil {
+		assigned := *s.lastAssigned
+		state.LastAssigned = &assigned
+	}
+	if !s.managerDown.IsZero() {
+		down := s.managerDown
+		state.ManagerDownSince = &down
+	}
+	return state
+}
+
+// History returns the most recent role transitions, oldest first
+func (s *Service) History() []Transition {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return append([]Transition(nil), s.history...)
+}
+
+// RuntimeState reports whether Ray is running and the role it was set up
+// for, in the form sent with heartbeats
+func (s *Service) RuntimeState() resource.RuntimeState {
+	state := resource.RuntimeState{Running: s.IsRunning()}
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	if s.applied != nil {
+		state.Role = string(s.applied.Role)
+		state.HeadIP = s.applied.HeadIP
+	}
+	return state
+}
@@ -0,0 +1,196 @@
+package ray
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray/raytest"
+)
+
+func TestReconcileTransitions(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestService(t, fake)
+
+	steps := []struct {
+		desired    RoleInfo
+		want       string
+		wantStarts int
+	}{
+		{desired: RoleInfo{Role: RoleNone}, want: ResultIdle},
+		{desired: RoleInfo{Role: RoleHead}, want: ResultStarted, wantStarts: 1},
+		{desired: RoleInfo{Role: RoleHead}, want: ResultUnchanged, wantStarts: 1},
+		{desired: RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.1"}, want: ResultRestarted, wantStarts: 2},
+		{desired: RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.1"}, want: ResultUnchanged, wantStarts: 2},
+		{desired: RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.2"}, want: ResultRestarted, wantStarts: 3},
+		{desired: RoleInfo{Role: RoleNone}, want: ResultStopped, wantStarts: 3},
+		{desired: RoleInfo{Role: RoleNone}, want: ResultIdle, wantStarts: 3},
+	}
+
+	for i, step := range steps {
+		got, err := s.Reconcile(context.Background(), step.desired, SourceManager)
+		if err != nil {
+			t.Fatalf("step %d: Reconcile(%+v): %v", i, step.desired, err)
+		}
+		if got != step.want {
+			t.Errorf("step %d: Reconcile(%+v) = %q, want %q", i, step.desired, got, step.want)
+		}
+		if starts := len(startCalls(fake)); starts != step.wantStarts {
+			t.Errorf("step %d: %d starts, want %d", i, starts, step.wantStarts)
+		}
+		if wantRunning := step.desired.Role != RoleNone; fake.Running() != wantRunning {
+			t.Errorf("step %d: running = %v, want %v", i, fake.Running(), wantRunning)
+		}
+	}
+
+	starts := startCalls(fake)
+	if !hasArg(starts[1], "10.0.0.1:6379") || !hasArg(starts[2], "10.0.0.2:6379") {
+		t.Errorf("worker starts = %v, want the head addresses", starts[1:])
+	}
+
+	// Every executed step is recorded; no-ops are not
+	if history := s.History(); len(history) != 4 {
+		t.Errorf("history has %d transitions, want 4", len(history))
+	}
+}
+
+func TestReconcileBackoff(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	fake.On("start", raytest.Response{Output: "start failed", Err: raytest.ErrExit})
+	s := newTestService(t, fake)
+
+	if _, err := s.Reconcile(context.Background(), RoleInfo{Role: RoleHead}, SourceManager); err == nil {
+		t.Fatal("Reconcile succeeded with a failing start")
+	}
+	state := s.RoleState()
+	if state.ConsecutiveFailures != 1 || state.NextAttempt == nil || state.LastError == "" {
+		t.Fatalf("state after failure = %+v", state)
+	}
+	if wait := time.Until(*state.NextAttempt); wait <= 0 || wait > minBackoff {
+		t.Errorf("next attempt in %s, want within %s", wait, minBackoff)
+	}
+
+	// Attempts are skipped until the backoff expires
+	_, err := s.Reconcile(context.Background(), RoleInfo{Role: RoleHead}, SourceManager)
+	if !errors.Is(err, ErrBackoff) {
+		t.Fatalf("Reconcile during backoff error = %v, want ErrBackoff", err)
+	}
+	if starts := len(startCalls(fake)); starts != 1 {
+		t.Errorf("%d starts during backoff, want 1", starts)
+	}
+
+	s.stateMu.Lock()
+	s.nextAttempt = time.Now().Add(-time.Second)
+	s.stateMu.Unlock()
+
+	result, err := s.Reconcile(context.Background(), RoleInfo{Role: RoleHead}, SourceManager)
+	if err != nil || result != ResultStarted {
+		t.Fatalf("Reconcile after backoff = %q, %v, want started", result, err)
+	}
+	if state := s.RoleState(); state.ConsecutiveFailures != 0 || state.NextAttempt != nil {
+		t.Errorf("backoff not reset after success: %+v", state)
+	}
+}
+
+func TestReconcileBackoffOnlyHoldsBackFailedRole(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	failed := raytest.Response{Output: "start failed", Err: raytest.ErrExit}
+	fake.On("start", failed, failed)
+	s := newTestService(t, fake)
+	worker := RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.1"}
+
+	if _, err := s.Reconcile(context.Background(), worker, SourceManager); err == nil {
+		t.Fatal("Reconcile succeeded with a failing start")
+	}
+
+	// A new head address is a different role and is tried at once
+	moved := RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.2"}
+	_, err := s.Reconcile(context.Background(), moved, SourceManager)
+	if err == nil || errors.Is(err, ErrBackoff) {
+		t.Fatalf("Reconcile with a new head = %v, want a start attempt", err)
+	}
+	if starts := len(startCalls(fake)); starts != 2 {
+		t.Errorf("%d starts, want 2", starts)
+	}
+	if state := s.RoleState(); state.ConsecutiveFailures != 1 {
+		t.Errorf("failures after the new role failed = %d, want 1", state.ConsecutiveFailures)
+	}
+
+	// The role that failed last is still held back
+	if _, err := s.Reconcile(context.Background(), moved, SourceManager); !errors.Is(err, ErrBackoff) {
+		t.Errorf("Reconcile retrying the failed role = %v, want ErrBackoff", err)
+	}
+
+	// Going idle while backing off needs no start and clears the backoff
+	result, err := s.Reconcile(context.Background(), RoleInfo{Role: RoleNone}, SourceManager)
+	if err != nil || result != ResultIdle {
+		t.Fatalf("Reconcile to none = %q, %v, want idle", result, err)
+	}
+	if state := s.RoleState(); state.ConsecutiveFailures != 0 || state.NextAttempt != nil {
+		t.Errorf("backoff after going idle = %+v, want reset", state)
+	}
+
+	result, err = s.Reconcile(context.Background(), worker, SourceManager)
+	if err != nil || result != ResultStarted {
+		t.Errorf("Reconcile to the first role again = %q, %v, want started", result, err)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, minBackoff},
+		{2, 2 * minBackoff},
+		{3, 4 * minBackoff},
+		{20, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.failures); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestReconcileAfterRestartKeepsRunningRay(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestService(t, fake)
+	worker := RoleInfo{Role: RoleWorker, HeadIP: "10.0.0.1"}
+	if _, err := s.Reconcile(context.Background(), worker, SourceManager); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	// A new daemon over the same data dir, with Ray left running
+	restarted := NewServiceWithRunner(s.config, nil, fake)
+	result, err := restarted.Reconcile(context.Background(), worker, SourceManager)
+	if err != nil || result != ResultUnchanged {
+		t.Fatalf("Reconcile after restart = %q, %v, want unchanged", result, err)
+	}
+	if subs := fake.Subcommands(); len(startCalls(fake)) != 1 || hasArg(subs, "stop") || hasArg(subs, "rm") {
+		t.Errorf("commands after restart = %v, want no stop, clear or second start", subs)
+	}
+
+	// A different role still restarts Ray
+	head := RoleInfo{Role: RoleHead}
+	again := NewServiceWithRunner(s.config, nil, fake)
+	if result, err := again.Reconcile(context.Background(), head, SourceManager); err != nil || result != ResultRestarted {
+		t.Errorf("Reconcile with a new role after restart = %q, %v, want restarted", result, err)
+	}
+}
+
+func TestReconcileAfterRestartWithRayStopped(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestService(t, fake)
+	if _, err := s.Reconcile(context.Background(), RoleInfo{Role: RoleHead}, SourceManager); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	fake.SetRunning(false)
+
+	restarted := NewServiceWithRunner(s.config, nil, fake)
+	result, err := restarted.Reconcile(context.Background(), RoleInfo{Role: RoleHead}, SourceManager)
+	if err != nil || result != ResultStarted {
+		t.Errorf("Reconcile after restart = %q, %v, want started", result, err)
+	}
+}
@@ -0,0 +1,64 @@
+package ray
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultCommandTimeout bounds a single Ray CLI invocation
+const defaultCommandTimeout = 2 * time.Minute
+
+// Runner executes the commands used to control Ray
+type Runner interface {
+	// Run executes name with args and returns the combined stdout and
+	// stderr output. The command must be aborted when ctx is done.
+	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// outputWaitDelay bounds how long a killed command's output is waited for.
+// Processes it spawned may hold the output open after it is gone.
+const outputWaitDelay = time.Second
+
+// ExecRunner runs commands as local processes
+type ExecRunner struct{}
+
+// Run executes the command as a child process
+func (ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = outputWaitDelay
+	return cmd.CombinedOutput()
+}
+
+// CommandError is a failed Ray CLI call. Its message stays short; the output
+// the command printed is attached to log records of the error instead.
+type CommandError struct {
+	Command string // Command line that failed
+	Output  string // Combined stdout and stderr
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Command, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// LogAttrs implements logging.Detailer
+func (e *CommandError) LogAttrs() []slog.Attr {
+	attrs := []slog.Attr{slog.String("command", e.Command)}
+	if e.Output != "" {
+		attrs = append(attrs, slog.String("output", e.Output))
+	}
+	return attrs
+}
+
+// commandLine formats a command for messages
+func commandLine(name string, args []string) string {
+	return strings.TrimSpace(name + " " + strings.Join(args, " "))
+}
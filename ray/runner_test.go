@@ -0,0 +1,167 @@
+package ray
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+)
+
+// newScriptService returns a service running raytest/fake-ray.sh as its
+// ray binary through ExecRunner, with the script's state in a temp dir
+func newScriptService(t *testing.T, mutate ...func(*config.Config)) (*Service, string) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run the fake ray script")
+	}
+	script, err := filepath.Abs(filepath.Join("raytest", "fake-ray.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := t.TempDir()
+	t.Setenv("FAKE_RAY_STATE", state)
+	t.Setenv("FAKE_RAY_FAIL", "")
+	t.Setenv("FAKE_RAY_DELAY", "")
+
+	cfg := &config.Config{
+		RayBinPath:        script,
+		RayCommandTimeout: 10 * time.Second,
+		RayHeadPort:       DefaultPort,
+		DataDir:           t.TempDir(),
+		RayTempDir:        t.TempDir(),
+	}
+	for _, fn := range mutate {
+		fn(cfg)
+	}
+	return NewServiceWithRunner(config.NewStore(cfg), nil, ExecRunner{}), state
+}
+
+// scriptCalls returns the command lines the fake script received
+func scriptCalls(t *testing.T, state string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(state, "calls.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestExecRunnerHeadLifecycle(t *testing.T) {
+	s, state := newScriptService(t)
+
+	if s.IsRunning() {
+		t.Fatal("Ray reported running before start")
+	}
+	if _, err := s.StartHead(0); err != nil {
+		t.Fatalf("StartHead: %v", err)
+	}
+	if !s.IsRunning() {
+		t.Fatal("Ray not running after StartHead")
+	}
+
+	status, err := s.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if status.Resources[ResourceCPU].Total == 0 {
+		t.Errorf("status = %+v, want the recorded CPU totals", status)
+	}
+
+	if _, err := s.StartHead(0); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("second StartHead = %v, want ErrAlreadyRunning", err)
+	}
+	if err := s.StopNode(); err != nil {
+		t.Fatalf("StopNode: %v", err)
+	}
+	if s.IsRunning() {
+		t.Error("Ray still running after StopNode")
+	}
+
+	calls := scriptCalls(t, state)
+	var starts []string
+	for _, call := range calls {
+		if strings.HasPrefix(call, "start") {
+			starts = append(starts, call)
+		}
+	}
+	if len(starts) != 1 || !strings.Contains(starts[0], "--head") || !strings.Contains(starts[0], "--port=6379") {
+		t.Errorf("start calls = %q, want one head start on port 6379", starts)
+	}
+}
+
+func TestExecRunnerWorker(t *testing.T) {
+	s, state := newScriptService(t)
+
+	if _, err := s.StartWorker("10.0.0.1", 0); err != nil {
+		t.Fatalf("StartWorker: %v", err)
+	}
+	running, err := os.ReadFile(filepath.Join(state, "running"))
+	if err != nil {
+		t.Fatalf("fake Ray not running: %v", err)
+	}
+	if !strings.Contains(string(running), "--address 10.0.0.1:6379") {
+		t.Errorf("worker started with %q, want the head address", running)
+	}
+}
+
+func TestExecRunnerCommandError(t *testing.T) {
+	s, _ := newScriptService(t)
+	t.Setenv("FAKE_RAY_FAIL", "start")
+
+	_, err := s.StartHead(0)
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("StartHead = %v, want a CommandError", err)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("error = %v, want exit status 1", err)
+	}
+	if cmdErr.Output != "fake-ray: start failed" || !strings.Contains(cmdErr.Command, "start --head") {
+		t.Errorf("CommandError = %q with output %q", cmdErr.Command, cmdErr.Output)
+	}
+
+	// Logging the wrapped error attaches the command and its output
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+	var buf bytes.Buffer
+	if err := logging.Setup(&buf, logging.FormatJSON, "info"); err != nil {
+		t.Fatal(err)
+	}
+	logging.For("ray").Error("Role change failed", "error", err)
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("decoding %q: %v", buf.String(), err)
+	}
+	if command, _ := rec["command"].(string); rec["output"] != "fake-ray: start failed" || !strings.Contains(command, "start --head") {
+		t.Errorf("logged %v, want the command and its output", rec)
+	}
+}
+
+func TestExecRunnerTimeout(t *testing.T) {
+	s, _ := newScriptService(t, func(c *config.Config) { c.RayCommandTimeout = 200 * time.Millisecond })
+	t.Setenv("FAKE_RAY_DELAY", "5")
+
+	start := time.Now()
+	_, err := s.StartHead(0)
+	elapsed := time.Since(start)
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("StartHead = %v, want a timeout", err)
+	}
+	// The status check and the start each hit the timeout; neither may
+	// wait for the script's sleep to finish
+	if elapsed > 3*time.Second {
+		t.Errorf("timed out commands took %v", elapsed)
+	}
+}
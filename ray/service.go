@@ -1,16 +1,23 @@
 package ray
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/exec"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/health"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/metrics"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
 	"github.com/unicornultrafoundation/subnet-rayai-node/resource"
 )
 
@@ -23,47 +30,215 @@ const (
 	RoleNone   NodeRole = "none"
 )
 
+// DefaultPort is the port a Ray head node listens on when none is given
+const DefaultPort = 6379
+
+var (
+	// ErrAlreadyRunning is returned when starting a node while Ray is already running
+	ErrAlreadyRunning = errors.New("ray is already running")
+	// ErrNotRunning is returned when stopping a node while Ray is not running
+	ErrNotRunning = errors.New("ray is not running")
+)
+
 // RoleInfo contains information about the node's role in the Ray cluster
 type RoleInfo struct {
-	Role   NodeRole `json:"role"`
-	HeadIP string   `json:"head_ip,omitempty"` // Only set for worker nodes
+	Role    NodeRole         `json:"role"`
+	HeadIP  string           `json:"head_ip,omitempty"` // Only set for worker nodes
+	Options *RayStartOptions `json:"options,omitempty"` // Overrides merged into the configured start options
+}
+
+// Equal reports whether r and other describe the same role assignment
+func (r RoleInfo) Equal(other RoleInfo) bool {
+	return r.Role == other.Role && r.HeadIP == other.HeadIP &&
+		reflect.DeepEqual(r.Options, other.Options)
 }
 
 // Service manages Ray processes on the local system
 type Service struct {
-	binPath     string
-	managerIP   string
-	client      *http.Client
-	resourceMgr *resource.Manager
+	binPath        string
+	config         *config.Store // Live configuration, for the manager endpoint
+	log            *slog.Logger
+	manager        *manager.Client
+	resourceMgr    *resource.Manager
+	runner         Runner
+	commandTimeout time.Duration
+	startOpts      RayStartOptions // Operator-configured `ray start` parameters
+	dataDir        string          // Holds the persisted role state
+
+	// Background loops started by StartPeriodicRoleSetup
+	wg sync.WaitGroup
+
+	// lifecycle is a one-slot lock serializing every start/stop of the
+	// local runtime; a channel so waiting for it can honor a context
+	lifecycle chan struct{}
+
+	// Role reconciliation state, guarded by stateMu so it can be read
+	// while a slow start/stop is in progress
+	stateMu     sync.Mutex
+	applied     *RoleInfo // Role the local runtime was last set up for, nil if unknown
+	failures    int       // Consecutive failed reconciliation attempts
+	nextAttempt time.Time // Earliest time of the next attempt after a failure
+	failedRole  *RoleInfo // Role the failures were for; another role is tried at once
+	lastError   string
+	history     []Transition
+	lastRunning bool      // Result of the last IsRunning check
+	lastChecked time.Time // Time of the last IsRunning check
+
+	// Role failure policy state, guarded by stateMu
+	lastAssigned *RoleInfo // Last role received from the manager, persisted
+	assignedAt   time.Time // When lastAssigned was received
+	managerDown  time.Time // First failed role request since the manager last answered
+
+	// Applied role saved before a restart, trusted by the first
+	// reconciliation if Ray is still running. Guarded by stateMu.
+	restored *RoleInfo
 }
 
 // NewService creates a new Ray service manager
-func NewService(cfg *config.Config, resourceMgr *resource.Manager) *Service {
-	if cfg.RayBinPath == "" {
-		cfg.RayBinPath = "ray" // Use ray from PATH if not specified
+func NewService(store *config.Store, resourceMgr *resource.Manager) *Service {
+	return NewServiceWithRunner(store, resourceMgr, ExecRunner{})
+}
+
+// NewServiceWithRunner creates a Ray service manager that executes commands
+// through runner
+func NewServiceWithRunner(store *config.Store, resourceMgr *resource.Manager, runner Runner) *Service {
+	cfg := store.Load()
+	binPath := cfg.RayBinPath
+	if binPath == "" {
+		binPath = "ray" // Use ray from PATH if not specified
+	}
+
+	commandTimeout := cfg.RayCommandTimeout
+	if commandTimeout <= 0 {
+		commandTimeout = defaultCommandTimeout
+	}
+
+	// Share the resource manager's signed client for manager requests
+	var managerClient *manager.Client
+	if resourceMgr != nil {
+		managerClient = resourceMgr.ManagerClient()
+	} else {
+		managerClient = manager.NewClient(store, &http.Client{})
+	}
+
+	s := &Service{
+		binPath:        binPath,
+		config:         store,
+		log:            logging.For("ray"),
+		resourceMgr:    resourceMgr,
+		runner:         runner,
+		commandTimeout: commandTimeout,
+		startOpts:      OptionsFromConfig(cfg),
+		dataDir:        cfg.DataDir,
+		manager:        managerClient,
+		lifecycle:      make(chan struct{}, 1),
+	}
+
+	// Restore the last assignment so the keep policy survives a restart
+	// while the manager is down, and the applied role so a runtime kept
+	// running across the restart is recognized
+	if state, err := loadRoleState(cfg.DataDir); err != nil {
+		s.log.Warn("Ignoring saved role", "error", err)
+	} else {
+		s.lastAssigned = state.Role
+		if state.ReceivedAt > 0 {
+			s.assignedAt = time.Unix(state.ReceivedAt, 0)
+		}
+		s.restored = state.Applied
 	}
+	return s
+}
 
-	service := &Service{
-		binPath:     cfg.RayBinPath,
-		managerIP:   cfg.ManagerIP,
-		resourceMgr: resourceMgr,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+// baseOptions returns the configured start options completed with the GPUs
+// the resource manager detected. Detection runs in the background, so this
+// is resolved when Ray starts rather than at construction.
+func (s *Service) baseOptions() RayStartOptions {
+	opts := s.startOpts
+	if s.resourceMgr != nil {
+		if res, err := s.resourceMgr.GetResources(false); err == nil {
+			opts = opts.WithDetectedGPUs(res.GPUVendor, res.GPUCount)
+		}
 	}
+	return opts
+}
 
-	// Start periodic role setup if requested
-	if cfg.ManagerIP != "" {
-		service.StartPeriodicRoleSetup()
+// lock acquires the lifecycle lock, giving up when ctx is done
+func (s *Service) lock(ctx context.Context) error {
+	select {
+	case s.lifecycle <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for another Ray start/stop: %w", ctx.Err())
 	}
+}
+
+// unlock releases the lifecycle lock
+func (s *Service) unlock() {
+	<-s.lifecycle
+}
 
-	return service
+// run executes a command through the service's runner, bounded by the
+// command timeout and aborted when ctx is done. Failures are returned as
+// *CommandError.
+func (s *Service) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmdCtx, cancel := context.WithTimeout(ctx, s.commandTimeout)
+	defer cancel()
+
+	output, err := s.runner.Run(cmdCtx, name, args...)
+	switch {
+	case ctx.Err() != nil:
+		err = fmt.Errorf("aborted: %w", ctx.Err())
+	case errors.Is(cmdCtx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("timed out after %s", s.commandTimeout)
+	}
+	if err != nil {
+		return output, &CommandError{
+			Command: commandLine(name, args),
+			Output:  strings.TrimSpace(string(output)),
+			Err:     err,
+		}
+	}
+	return output, nil
 }
 
 // IsRunning checks if any Ray node is currently running
 func (s *Service) IsRunning() bool {
-	cmd := exec.Command(s.binPath, "status")
-	output, err := cmd.CombinedOutput()
+	return s.isRunning(context.Background())
+}
+
+// isRunning implements IsRunning, aborting the check when ctx is done
+func (s *Service) isRunning(ctx context.Context) bool {
+	running := s.checkRunning(ctx)
+	s.recordRunning(running)
+	return running
+}
+
+// recordRunning stores the result of a running check, or of a start or
+// stop, for RunningWithin
+func (s *Service) recordRunning(running bool) {
+	metrics.RayUp.Set(metrics.BoolValue(running))
+
+	s.stateMu.Lock()
+	s.lastRunning, s.lastChecked = running, time.Now()
+	s.stateMu.Unlock()
+}
+
+// RunningWithin reports whether Ray is running, reusing the result of a
+// check made within maxAge rather than running `ray status` again
+func (s *Service) RunningWithin(maxAge time.Duration) bool {
+	s.stateMu.Lock()
+	running, checked := s.lastRunning, s.lastChecked
+	s.stateMu.Unlock()
+
+	if !checked.IsZero() && time.Since(checked) < maxAge {
+		return running
+	}
+	return s.IsRunning()
+}
+
+// checkRunning runs `ray status` to find whether Ray is running
+func (s *Service) checkRunning(ctx context.Context) bool {
+	output, err := s.run(ctx, s.binPath, "status")
 	if err != nil {
 		// If we get an error, assume Ray is not running
 		return false
@@ -77,38 +252,27 @@ func (s *Service) IsRunning() bool {
 }
 
 // GetRole queries the manager to determine this node's role (head or worker)
-func (s *Service) GetRole() (*RoleInfo, error) {
-	if s.managerIP == "" {
+func (s *Service) GetRole(ctx context.Context) (*RoleInfo, error) {
+	if !s.manager.Configured() {
 		// If no manager is configured, assume head node by default
 		return &RoleInfo{Role: RoleHead}, nil
 	}
 
-	// Call manager API to get role assignment
-	url := fmt.Sprintf("http://%s/api/node/role", s.managerIP)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	// Identify ourselves when the manager has assigned us an ID
+	var nodeID string
+	if s.resourceMgr != nil {
+		nodeID = s.resourceMgr.NodeID()
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := s.client.Do(req)
+	assignment, err := s.manager.Role(ctx, nodeID)
 	if err != nil {
-		// If manager is unreachable, default to head role for resilience
-		log.Printf("Failed to reach manager at %s: %v, defaulting to head node role", s.managerIP, err)
-		return &RoleInfo{Role: RoleHead}, nil
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		// If manager returns an error, log it and default to head role
-		log.Printf("Manager returned status %d, defaulting to head node role", resp.StatusCode)
-		return &RoleInfo{Role: RoleHead}, nil
+		return nil, err
 	}
 
-	// Parse the response
-	var roleInfo RoleInfo
-	if err := json.NewDecoder(resp.Body).Decode(&roleInfo); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	roleInfo := RoleInfo{Role: NodeRole(assignment.Role), HeadIP: assignment.HeadIP}
+	if len(assignment.Options) > 0 {
+		if err := json.Unmarshal(assignment.Options, &roleInfo.Options); err != nil {
+			return nil, fmt.Errorf("failed to decode role options: %w", err)
+		}
 	}
 
 	// Validate the role (now including RoleNone as valid)
@@ -121,138 +285,167 @@ func (s *Service) GetRole() (*RoleInfo, error) {
 		return nil, fmt.Errorf("received worker role from manager but no head IP was provided")
 	}
 
-	log.Printf("Node assigned role: %s", roleInfo.Role)
-	if roleInfo.Role == RoleWorker {
-		log.Printf("Will connect to head node at: %s", roleInfo.HeadIP)
-	} else if roleInfo.Role == RoleNone {
-		log.Printf("No role assigned, node will be stopped if running")
+	switch roleInfo.Role {
+	case RoleWorker:
+		s.log.Debug("Manager assigned role", "assigned_role", roleInfo.Role, "head_ip", roleInfo.HeadIP)
+	case RoleNone:
+		s.log.Debug("Manager assigned no role, node will be stopped if running")
+	default:
+		s.log.Debug("Manager assigned role", "assigned_role", roleInfo.Role)
 	}
 
 	return &roleInfo, nil
 }
 
-// SetupNodeByRole automatically sets up the node based on its assigned role
-func (s *Service) SetupNodeByRole() (string, error) {
-	// Get the node's role from manager
-	roleInfo, err := s.GetRole()
+// SetupNodeByRole automatically sets up the node based on its assigned role,
+// or the role failure policy while the manager can't be reached
+func (s *Service) SetupNodeByRole(ctx context.Context) (string, error) {
+	roleInfo, source, err := s.desiredRole(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to determine node role: %w", err)
 	}
 
-	// Handle the case of no assigned role
-	if roleInfo.Role == RoleNone {
-		// If Ray is running, stop it
-		if s.IsRunning() {
-			log.Printf("No role assigned, stopping Ray")
-			if err := s.StopNode(); err != nil {
-				return "", fmt.Errorf("failed to stop Ray: %w", err)
-			}
-
-			// Clear data
-			if err := s.ClearRayData(); err != nil {
-				log.Printf("Warning: failed to clear Ray data: %v", err)
-				// Continue despite errors
-			}
+	return s.Reconcile(ctx, *roleInfo, source)
+}
 
-			return "stopped", nil
-		}
-		return "idle", nil
+// StartHead starts a Ray head node listening on the given port.
+// A zero port selects the configured head port.
+func (s *Service) StartHead(port int) (string, error) {
+	ctx := context.Background()
+	if err := s.lock(ctx); err != nil {
+		return "", err
 	}
+	defer s.unlock()
 
 	// Check if Ray is already running
-	if s.IsRunning() {
-		return "", fmt.Errorf("ray is already running, please stop it first")
+	if s.isRunning(ctx) {
+		return "", fmt.Errorf("%w, please stop it first", ErrAlreadyRunning)
 	}
 
-	// Set up based on role
-	switch roleInfo.Role {
-	case RoleHead:
-		return s.StartHead()
-	case RoleWorker:
-		if roleInfo.HeadIP == "" {
-			return "", fmt.Errorf("cannot start worker: no head IP provided")
-		}
-		return s.StartWorker(roleInfo.HeadIP)
-	default:
-		return "", fmt.Errorf("unknown role: %s", roleInfo.Role)
+	opts := s.baseOptions()
+	if port != 0 {
+		opts.Port = port
+	}
+
+	id, err := s.startHead(ctx, opts)
+	s.finishTransition(RoleInfo{Role: RoleHead}, SourceAPI, ResultStarted, err)
+	if err != nil {
+		return "", err
 	}
+
+	return id, nil
 }
 
-// StartHead starts a Ray head node
-func (s *Service) StartHead() (string, error) {
-	// Check if Ray is already running
-	if s.IsRunning() {
-		return "", fmt.Errorf("ray is already running, please stop it first")
+// StartWorker starts a Ray worker node connecting to the head at headIP:port.
+// A zero port selects the configured head port.
+func (s *Service) StartWorker(headIP string, port int) (string, error) {
+	ctx := context.Background()
+	if err := s.lock(ctx); err != nil {
+		return "", err
 	}
+	defer s.unlock()
 
-	args := []string{
-		"start",
-		"--head",
-		"--port=6379",
-		"--dashboard-host=0.0.0.0",
+	// Check if Ray is already running locally
+	if s.isRunning(ctx) {
+		return "", fmt.Errorf("%w locally, please stop it first", ErrAlreadyRunning)
 	}
 
-	cmd := exec.Command(s.binPath, args...)
+	opts := s.baseOptions()
+	if port != 0 {
+		opts.Port = port
+	}
 
-	output, err := cmd.CombinedOutput()
+	id, err := s.startWorker(ctx, headIP, opts)
+	s.finishTransition(RoleInfo{Role: RoleWorker, HeadIP: headIP}, SourceAPI, ResultStarted, err)
 	if err != nil {
-		return "", fmt.Errorf("failed to start Ray head node: %w, output: %s", err, string(output))
+		return "", err
 	}
 
-	// Extract process ID or use port as identifier
-	id := fmt.Sprintf("head-%d", 6379)
-	log.Printf("Started Ray head node on port %d", 6379)
-
 	return id, nil
 }
 
-// StartWorker starts a Ray worker node
-func (s *Service) StartWorker(headIP string) (string, error) {
-	// Check if Ray is already running locally
-	if s.IsRunning() {
-		return "", fmt.Errorf("ray is already running locally, please stop it first")
+// StopNode stops all Ray nodes (head and workers)
+func (s *Service) StopNode() error {
+	ctx := context.Background()
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	// Check if Ray is running before trying to stop it
+	if !s.isRunning(ctx) {
+		return fmt.Errorf("%w, nothing to stop", ErrNotRunning)
 	}
 
-	args := []string{
-		"start",
-		"--address", fmt.Sprintf("%s:6379", headIP),
+	err := s.stopNode(ctx)
+	s.finishTransition(RoleInfo{Role: RoleNone}, SourceAPI, ResultStopped, err)
+	if err != nil {
+		return err
 	}
 
-	cmd := exec.Command(s.binPath, args...)
+	return nil
+}
+
+// startHead runs `ray start --head` without checking the current state
+func (s *Service) startHead(ctx context.Context, opts RayStartOptions) (string, error) {
+	args := opts.HeadArgs()
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("failed to start Ray worker node: %w, output: %s", err, string(output))
+	if _, err := s.run(ctx, s.binPath, args...); err != nil {
+		return "", fmt.Errorf("failed to start Ray head node: %w", err)
+	}
+	s.recordRunning(true)
+
+	// Extract process ID or use port as identifier
+	id := fmt.Sprintf("head-%d", opts.Port)
+	s.log.Info("Started Ray head node", "port", opts.Port)
+
+	return id, nil
+}
+
+// startWorker runs `ray start --address` without checking the current state
+func (s *Service) startWorker(ctx context.Context, headIP string, opts RayStartOptions) (string, error) {
+	if headIP == "" {
+		return "", fmt.Errorf("cannot start worker: no head IP provided")
+	}
+
+	args := opts.WorkerArgs(headIP)
+
+	if _, err := s.run(ctx, s.binPath, args...); err != nil {
+		return "", fmt.Errorf("failed to start Ray worker node: %w", err)
 	}
+	s.recordRunning(true)
 
 	// Generate a session ID for this worker
 	id := fmt.Sprintf("worker-%s", strings.Replace(headIP, ".", "-", -1))
-	log.Printf("Started Ray worker node connecting to %s", headIP)
+	s.log.Info("Started Ray worker node", "head_ip", headIP)
 
 	return id, nil
 }
 
-// StopNode stops all Ray nodes (head and workers)
-func (s *Service) StopNode() error {
-	// Check if Ray is running before trying to stop it
-	if !s.IsRunning() {
-		return fmt.Errorf("ray is not running, nothing to stop")
+// stopNode runs `ray stop` without checking the current state
+func (s *Service) stopNode(ctx context.Context) error {
+	if _, err := s.run(ctx, s.binPath, "stop"); err != nil {
+		return fmt.Errorf("failed to stop Ray nodes: %w", err)
 	}
+	s.recordRunning(false)
 
-	cmd := exec.Command(s.binPath, "stop")
-	output, err := cmd.CombinedOutput()
+	s.log.Info("Stopped all Ray nodes")
+	return nil
+}
+
+// GetStatus returns the parsed status of the Ray cluster
+func (s *Service) GetStatus() (*ClusterStatus, error) {
+	output, err := s.GetRawStatus()
 	if err != nil {
-		return fmt.Errorf("failed to stop Ray nodes: %w, output: %s", err, string(output))
+		return nil, err
 	}
 
-	log.Printf("Stopped all Ray nodes")
-	return nil
+	return ParseStatus(output)
 }
 
-// GetStatus returns the status of Ray cluster
-func (s *Service) GetStatus() (string, error) {
-	cmd := exec.Command(s.binPath, "status")
-	output, err := cmd.CombinedOutput()
+// GetRawStatus returns the unparsed output of `ray status`
+func (s *Service) GetRawStatus() (string, error) {
+	output, err := s.run(context.Background(), s.binPath, "status")
 	if err != nil {
 		return "", fmt.Errorf("failed to get Ray status: %w", err)
 	}
@@ -260,10 +453,28 @@ func (s *Service) GetStatus() (string, error) {
 	return string(output), nil
 }
 
+// Busy reports whether the cluster is running work: CPUs or GPUs in use or
+// resource demands waiting. A stopped or unreadable Ray counts as idle.
+func (s *Service) Busy() bool {
+	status, err := s.GetStatus()
+	if err != nil {
+		return false
+	}
+	for _, name := range []string{ResourceCPU, ResourceGPU} {
+		if status.Resources[name].Used > 0 {
+			return true
+		}
+	}
+	return len(status.Demands) > 0
+}
+
 // ClearRayData removes Ray session temporary files
-func (s *Service) ClearRayData() error {
-	// Ray stores session data in /tmp/ray
-	rayDataDir := "/tmp/ray"
+func (s *Service) ClearRayData(ctx context.Context) error {
+	// Ray stores session data in its temp dir, /tmp/ray unless configured
+	rayDataDir := s.startOpts.TempDir
+	if rayDataDir == "" {
+		rayDataDir = "/tmp/ray"
+	}
 
 	// Check if directory exists
 	_, err := os.Stat(rayDataDir)
@@ -273,51 +484,100 @@ func (s *Service) ClearRayData() error {
 	}
 
 	// Execute rm command for safety (more controlled than os.RemoveAll)
-	cmd := exec.Command("rm", "-rf", rayDataDir)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to clear Ray data: %w, output: %s", err, string(output))
+	if _, err := s.run(ctx, "rm", "-rf", rayDataDir); err != nil {
+		return fmt.Errorf("failed to clear Ray data: %w", err)
 	}
 
-	log.Printf("Cleared Ray data directory: %s", rayDataDir)
+	s.log.Info("Cleared Ray data directory", "path", rayDataDir)
 	return nil
 }
 
-// Add a new method to start a periodic role checker
-
 // StartPeriodicRoleSetup starts a background goroutine that checks and sets up
-// the node's role every minute
-func (s *Service) StartPeriodicRoleSetup() {
+// the node's role every minute until ctx is done, beating loop after each
+// check. Checks are skipped while no manager is configured, and run at once
+// when the configuration is reloaded.
+func (s *Service) StartPeriodicRoleSetup(ctx context.Context, loop *health.Loop) {
+	// A check may run ray status, stop and start, each bounded by the
+	// command timeout, plus a request to the manager
+	within := time.Minute + 4*s.commandTimeout
+	loop.Beat(within)
+
+	s.wg.Add(1)
 	go func() {
+		defer s.wg.Done()
+
 		// Initial setup without delay
-		if _, err := s.SetupNodeByRole(); err != nil {
-			log.Printf("Initial role setup failed: %v", err)
+		if s.config.Load().ManagerIP != "" {
+			if _, err := s.SetupNodeByRole(ctx); err != nil {
+				s.log.Error("Initial role setup failed", "error", err)
+			}
 		}
+		loop.Beat(within)
 
 		// Setup ticker for periodic checks
 		ticker := time.NewTicker(time.Minute)
 		defer ticker.Stop()
 
-		for range ticker.C {
-			log.Printf("Periodic role check running...")
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.config.Changed():
+			}
 
-			// Check current role
-			currentRole, err := s.GetRole()
-			if err != nil {
-				log.Printf("Failed to get current role: %v", err)
+			if s.config.Load().ManagerIP == "" {
+				loop.Beat(within)
 				continue
 			}
 
-			// Setup node according to the current role
-			result, err := s.SetupNodeByRole()
+			// Converge the node to the currently assigned role
+			result, err := s.SetupNodeByRole(ctx)
+			loop.Beat(within)
 			if err != nil {
-				log.Printf("Role setup failed: %v", err)
+				s.log.Error("Role setup failed", "error", err)
 				continue
 			}
 
-			log.Printf("Role check completed: %s (%s)", currentRole.Role, result)
+			s.log.Debug("Role check completed", "result", result)
 		}
 	}()
 
-	log.Println("Started periodic role checking (every 1 minute)")
+	s.log.Info("Started periodic role checking", "interval", time.Minute)
+}
+
+// Wait blocks until the background loops have exited
+func (s *Service) Wait() {
+	s.wg.Wait()
+}
+
+// Drain stops the local Ray runtime as part of daemon shutdown. With force
+// set, Ray processes are killed instead of being given a grace period.
+// It is a no-op when Ray is not running, and gives up when ctx is done.
+func (s *Service) Drain(ctx context.Context, force bool) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	if !s.isRunning(ctx) {
+		return nil
+	}
+
+	args := []string{"stop"}
+	if force {
+		args = append(args, "--force")
+	}
+
+	_, err := s.run(ctx, s.binPath, args...)
+	if err != nil {
+		err = fmt.Errorf("failed to stop Ray nodes: %w", err)
+	}
+	s.finishTransition(RoleInfo{Role: RoleNone}, SourceShutdown, ResultStopped, err)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Stopped Ray for shutdown")
+	return nil
 }
@@ -0,0 +1,252 @@
+package ray
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/ray/raytest"
+)
+
+// newTestService returns a service driving runner, with its state and Ray
+// temp dir in temporary directories
+func newTestService(t *testing.T, runner Runner, mutate ...func(*config.Config)) *Service {
+	t.Helper()
+	cfg := &config.Config{
+		RayBinPath:        "ray",
+		RayCommandTimeout: 5 * time.Second,
+		RayHeadPort:       DefaultPort,
+		DataDir:           t.TempDir(),
+		RayTempDir:        t.TempDir(),
+	}
+	for _, fn := range mutate {
+		fn(cfg)
+	}
+	return NewServiceWithRunner(config.NewStore(cfg), nil, runner)
+}
+
+// startCalls returns the arguments of every `ray start` run by fake
+func startCalls(fake *raytest.FakeRunner) [][]string {
+	var calls [][]string
+	for _, call := range fake.Calls() {
+		if len(call.Args) > 0 && call.Args[0] == "start" {
+			calls = append(calls, call.Args)
+		}
+	}
+	return calls
+}
+
+// hasArg reports whether want is one of args
+func hasArg(args []string, want string) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStartHead(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestService(t, fake)
+
+	id, err := s.StartHead(0)
+	if err != nil {
+		t.Fatalf("StartHead: %v", err)
+	}
+	if id != "head-6379" {
+		t.Errorf("id = %q, want head-6379", id)
+	}
+	if !fake.Running() {
+		t.Error("Ray not running after StartHead")
+	}
+
+	starts := startCalls(fake)
+	if len(starts) != 1 || !hasArg(starts[0], "--head") || !hasArg(starts[0], "--port=6379") {
+		t.Errorf("start calls = %v, want one head start on port 6379", starts)
+	}
+
+	if _, err := s.StartHead(0); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("second StartHead error = %v, want ErrAlreadyRunning", err)
+	}
+	if applied := s.RoleState().Applied; applied == nil || applied.Role != RoleHead {
+		t.Errorf("applied role = %+v, want head", applied)
+	}
+}
+
+func TestStartWorker(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestService(t, fake)
+
+	if _, err := s.StartWorker("10.0.0.1", 7000); err != nil {
+		t.Fatalf("StartWorker: %v", err)
+	}
+
+	starts := startCalls(fake)
+	want := []string{"start", "--address", "10.0.0.1:7000"}
+	if len(starts) != 1 || strings.Join(starts[0], " ") != strings.Join(want, " ") {
+		t.Errorf("start calls = %v, want %v", starts, want)
+	}
+}
+
+func TestStopNode(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestService(t, fake)
+
+	if err := s.StopNode(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("StopNode while stopped error = %v, want ErrNotRunning", err)
+	}
+
+	fake.SetRunning(true)
+	if err := s.StopNode(); err != nil {
+		t.Fatalf("StopNode: %v", err)
+	}
+	if fake.Running() {
+		t.Error("Ray still running after StopNode")
+	}
+}
+
+func TestStartFailureOutput(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	fake.On("start", raytest.Response{Output: "RuntimeError: port 6379 in use\n", Err: raytest.ErrExit})
+	s := newTestService(t, fake)
+
+	_, err := s.StartHead(0)
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("StartHead error = %v, want a CommandError", err)
+	}
+	if cmdErr.Output != "RuntimeError: port 6379 in use" {
+		t.Errorf("output = %q", cmdErr.Output)
+	}
+	if !errors.Is(err, raytest.ErrExit) {
+		t.Errorf("error %v does not wrap the exit error", err)
+	}
+	if s.RoleState().Applied != nil {
+		t.Error("applied role set after a failed start")
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	fake.On("start", raytest.Response{Delay: time.Minute})
+	s := newTestService(t, fake, func(cfg *config.Config) {
+		cfg.RayCommandTimeout = 50 * time.Millisecond
+	})
+
+	began := time.Now()
+	_, err := s.StartHead(0)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Fatalf("StartHead error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(began); elapsed > 5*time.Second {
+		t.Errorf("StartHead took %s, want it cut short by the command timeout", elapsed)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	s := newTestService(t, fake)
+
+	if _, err := s.GetStatus(); err == nil {
+		t.Error("GetStatus succeeded while Ray is stopped")
+	}
+
+	fake.SetRunning(true)
+	status, err := s.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if got := status.Resources[ResourceCPU].Total; got != 4 {
+		t.Errorf("CPU total = %v, want 4", got)
+	}
+	if s.Busy() {
+		t.Error("Busy with no resources in use")
+	}
+}
+
+func TestReconcileAbortedByContext(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	fake.On("start", raytest.Response{Delay: time.Minute})
+	s := newTestService(t, fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	began := time.Now()
+	_, err := s.Reconcile(ctx, RoleInfo{Role: RoleHead}, SourceManager)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Reconcile error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(began); elapsed > 5*time.Second {
+		t.Errorf("Reconcile took %s after its context was cancelled", elapsed)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name  string
+		force bool
+		want  []string
+	}{
+		{name: "graceful", want: []string{"stop"}},
+		{name: "force", force: true, want: []string{"stop", "--force"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := raytest.NewFakeRunner()
+			fake.SetRunning(true)
+			s := newTestService(t, fake)
+
+			if err := s.Drain(context.Background(), tt.force); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+			calls := fake.Calls()
+			last := calls[len(calls)-1].Args
+			if strings.Join(last, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("last command = %v, want %v", last, tt.want)
+			}
+			if fake.Running() {
+				t.Error("Ray still running after Drain")
+			}
+		})
+	}
+}
+
+func TestDrainRespectsContext(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	fake.SetRunning(true)
+	s := newTestService(t, fake)
+
+	// Another start/stop holds the lifecycle lock
+	if err := s.lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain error = %v, want context.DeadlineExceeded", err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("Drain ran %v while the lock was held", fake.Subcommands())
+	}
+}
+
+func TestDrainAbortsSlowStop(t *testing.T) {
+	fake := raytest.NewFakeRunner()
+	fake.SetRunning(true)
+	fake.On("stop", raytest.Response{Delay: time.Minute})
+	s := newTestService(t, fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain error = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,85 @@
+package ray
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// roleFileName is the file under the data directory holding the last role
+// received from the manager and the role Ray was set up for
+const roleFileName = "role.json"
+
+// roleState is persisted under the data directory so the keep failure
+// policy can restore the role after a restart while the manager is down,
+// and a Ray runtime left running across a restart isn't restarted needlessly
+type roleState struct {
+	Role       *RoleInfo `json:"role"`
+	ReceivedAt int64     `json:"received_at,omitempty"`
+	Applied    *RoleInfo `json:"applied,omitempty"` // Role the running Ray runtime was set up for
+}
+
+// loadRoleState reads the persisted role, returning an empty state when
+// none has been written yet
+func loadRoleState(dataDir string) (*roleState, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, roleFileName))
+	if os.IsNotExist(err) {
+		return &roleState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role state: %w", err)
+	}
+
+	var state roleState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse role state: %w", err)
+	}
+	return &state, nil
+}
+
+// saveRoleState atomically writes the role state to the data directory
+func saveRoleState(dataDir string, state *roleState) error {
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal role state: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(dataDir, roleFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write role state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write role state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write role state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dataDir, roleFileName)); err != nil {
+		return fmt.Errorf("failed to write role state: %w", err)
+	}
+	return nil
+}
+
+// saveRoles persists the last assigned and the applied role, logging
+// failures: losing them only costs a restart of Ray after the next boot
+func (s *Service) saveRoles() {
+	s.stateMu.Lock()
+	state := &roleState{Role: s.lastAssigned, Applied: s.applied}
+	if !s.assignedAt.IsZero() {
+		state.ReceivedAt = s.assignedAt.Unix()
+	}
+	s.stateMu.Unlock()
+
+	if err := saveRoleState(s.dataDir, state); err != nil {
+		s.log.Warn("Failed to persist role", "error", err)
+	}
+}
@@ -0,0 +1,286 @@
+package ray
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Resource names reported by `ray status` for the built-in resources
+const (
+	ResourceCPU         = "CPU"
+	ResourceGPU         = "GPU"
+	ResourceMemory      = "memory"
+	ResourceObjectStore = "object_store_memory"
+)
+
+// ClusterStatus is the parsed output of `ray status`
+type ClusterStatus struct {
+	Timestamp    string                   `json:"timestamp,omitempty"`
+	ActiveNodes  []NodeGroup              `json:"active_nodes"`
+	IdleNodes    []NodeGroup              `json:"idle_nodes"`
+	PendingNodes []PendingNode            `json:"pending_nodes"`
+	FailedNodes  []FailedNode             `json:"failed_nodes"`
+	Resources    map[string]ResourceUsage `json:"resources"` // Keyed by resource name, e.g. CPU, GPU, memory
+	Demands      []ResourceDemand         `json:"demands"`
+	// Unparsed holds lines inside a known section that the parser did not
+	// understand, so a new Ray release degrades the output instead of failing it
+	Unparsed []string `json:"unparsed,omitempty"`
+}
+
+// NodeGroup is a number of nodes sharing a node ID or node type
+type NodeGroup struct {
+	Count int    `json:"count"`
+	Name  string `json:"name"`
+}
+
+// PendingNode is a node the autoscaler is still launching
+type PendingNode struct {
+	Address  string `json:"address,omitempty"`
+	NodeType string `json:"node_type"`
+	Status   string `json:"status,omitempty"`
+}
+
+// FailedNode is a node that recently failed
+type FailedNode struct {
+	NodeType string `json:"node_type"`
+	Reason   string `json:"reason"`
+	Address  string `json:"address,omitempty"`
+}
+
+// ResourceUsage is the used and total amount of a cluster resource.
+// Memory resources are expressed in bytes.
+type ResourceUsage struct {
+	Used  float64 `json:"used"`
+	Total float64 `json:"total"`
+}
+
+// ResourceDemand is a resource shape waiting to be scheduled
+type ResourceDemand struct {
+	Shape string `json:"shape"`          // e.g. {'CPU': 1.0}
+	Count int    `json:"count"`          // Lower bound on the number of pending requests
+	Kind  string `json:"kind,omitempty"` // e.g. "pending tasks/actors"
+}
+
+// statusSection identifies which part of the `ray status` output is being parsed
+type statusSection int
+
+const (
+	sectionNone statusSection = iota
+	sectionActive
+	sectionIdle
+	sectionPending
+	sectionFailures
+	sectionUsage
+	sectionDemands
+	sectionIgnored
+)
+
+var (
+	timestampRe = regexp.MustCompile(`Autoscaler status:\s*(.*?)\s*=+\s*$`)
+	nodeGroupRe = regexp.MustCompile(`^(\d+)\s+(\S+)$`)
+	// Matches both "0B/30.50GiB memory" and the older "0.00/30.500 GiB memory"
+	usageRe      = regexp.MustCompile(`^([\d.]+)\s*([KMGTP]i?B|B)?/([\d.]+)\s*([KMGTP]i?B|B)?\s+(\S+)`)
+	demandRe     = regexp.MustCompile(`^(.+):\s*(\d+)\+?\s*(.*)$`)
+	failedAddrRe = regexp.MustCompile(`\(ip:\s*([^)]+)\)`)
+)
+
+// byteUnits maps the size suffixes used by `ray status` to their multipliers
+var byteUnits = map[string]float64{
+	"B":   1,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"PiB": 1 << 50,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+}
+
+// ParseStatus parses the output of `ray status` into a ClusterStatus. It
+// fails only when the output is not recognizable as `ray status` at all;
+// individual lines it cannot parse are collected in Unparsed.
+func ParseStatus(output string) (*ClusterStatus, error) {
+	status := &ClusterStatus{
+		ActiveNodes:  []NodeGroup{},
+		IdleNodes:    []NodeGroup{},
+		PendingNodes: []PendingNode{},
+		FailedNodes:  []FailedNode{},
+		Resources:    map[string]ResourceUsage{},
+		Demands:      []ResourceDemand{},
+	}
+
+	recognized := false
+	section := sectionNone
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := timestampRe.FindStringSubmatch(line); m != nil {
+			status.Timestamp = m[1]
+			continue
+		}
+
+		switch line {
+		case "":
+			continue
+		case "Node status", "Resources":
+			recognized = true
+			section = sectionNone
+			continue
+		case "Active:", "Healthy:":
+			section = sectionActive
+			continue
+		case "Idle:":
+			section = sectionIdle
+			continue
+		case "Pending:":
+			section = sectionPending
+			continue
+		case "Recent failures:":
+			section = sectionFailures
+			continue
+		case "Usage:", "Total Usage:":
+			section = sectionUsage
+			continue
+		case "Demands:", "Total Demands:":
+			section = sectionDemands
+			continue
+		case "Total Constraints:", "From request_resources:", "Pending Demands:":
+			section = sectionIgnored
+			continue
+		}
+
+		// Separators and "(no pending nodes)"-style placeholders carry no data
+		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "(no ") {
+			continue
+		}
+
+		var err error
+		switch section {
+		case sectionActive, sectionIdle:
+			var group NodeGroup
+			if group, err = parseNodeGroup(line); err == nil {
+				if section == sectionActive {
+					status.ActiveNodes = append(status.ActiveNodes, group)
+				} else {
+					status.IdleNodes = append(status.IdleNodes, group)
+				}
+			}
+		case sectionPending:
+			status.PendingNodes = append(status.PendingNodes, parsePendingNode(line))
+		case sectionFailures:
+			status.FailedNodes = append(status.FailedNodes, parseFailedNode(line))
+		case sectionUsage:
+			var name string
+			var usage ResourceUsage
+			if name, usage, err = parseResourceUsage(line); err == nil {
+				status.Resources[name] = usage
+			}
+		case sectionDemands:
+			var demand ResourceDemand
+			if demand, err = parseDemand(line); err == nil {
+				status.Demands = append(status.Demands, demand)
+			}
+		}
+		if err != nil {
+			status.Unparsed = append(status.Unparsed, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ray status output: %w", err)
+	}
+
+	if !recognized {
+		return nil, fmt.Errorf("unrecognized ray status output")
+	}
+
+	return status, nil
+}
+
+// parseNodeGroup parses lines like "1 node_2b3c..." or "2 ray.worker.default"
+func parseNodeGroup(line string) (NodeGroup, error) {
+	m := nodeGroupRe.FindStringSubmatch(line)
+	if m == nil {
+		return NodeGroup{}, fmt.Errorf("invalid node line: %q", line)
+	}
+	count, _ := strconv.Atoi(m[1])
+	return NodeGroup{Count: count, Name: m[2]}, nil
+}
+
+// parsePendingNode parses lines like "10.0.0.5: ray.worker.gpu, setting up"
+func parsePendingNode(line string) PendingNode {
+	var node PendingNode
+	if addr, rest, ok := strings.Cut(line, ": "); ok {
+		node.Address = addr
+		line = rest
+	}
+	nodeType, nodeStatus, _ := strings.Cut(line, ",")
+	node.NodeType = strings.TrimSpace(nodeType)
+	node.Status = strings.TrimSpace(nodeStatus)
+	return node
+}
+
+// parseFailedNode parses lines like "ray.worker.cpu: NodeTerminated (ip: 10.0.0.7)"
+func parseFailedNode(line string) FailedNode {
+	var node FailedNode
+	nodeType, reason, _ := strings.Cut(line, ":")
+	node.NodeType = strings.TrimSpace(nodeType)
+	if m := failedAddrRe.FindStringSubmatch(reason); m != nil {
+		node.Address = strings.TrimSpace(m[1])
+		reason = strings.Replace(reason, m[0], "", 1)
+	}
+	node.Reason = strings.TrimSpace(reason)
+	return node
+}
+
+// parseResourceUsage parses lines like "0.0/16.0 CPU" or "0B/30.50GiB memory"
+func parseResourceUsage(line string) (string, ResourceUsage, error) {
+	m := usageRe.FindStringSubmatch(line)
+	if m == nil {
+		return "", ResourceUsage{}, fmt.Errorf("invalid resource usage line: %q", line)
+	}
+
+	usedUnit, totalUnit := m[2], m[4]
+	if usedUnit == "" {
+		usedUnit = totalUnit
+	}
+
+	used, err := parseQuantity(m[1], usedUnit)
+	if err != nil {
+		return "", ResourceUsage{}, err
+	}
+	total, err := parseQuantity(m[3], totalUnit)
+	if err != nil {
+		return "", ResourceUsage{}, err
+	}
+
+	return m[5], ResourceUsage{Used: used, Total: total}, nil
+}
+
+// parseQuantity parses a number with an optional byte unit suffix
+func parseQuantity(value, unit string) (float64, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", value, err)
+	}
+	if unit == "" {
+		return v, nil
+	}
+	return v * byteUnits[unit], nil
+}
+
+// parseDemand parses lines like "{'CPU': 1.0}: 10+ pending tasks/actors"
+func parseDemand(line string) (ResourceDemand, error) {
+	m := demandRe.FindStringSubmatch(line)
+	if m == nil {
+		return ResourceDemand{}, fmt.Errorf("invalid demand line: %q", line)
+	}
+	count, _ := strconv.Atoi(m[2])
+	return ResourceDemand{Shape: m[1], Count: count, Kind: m[3]}, nil
+}
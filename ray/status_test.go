@@ -0,0 +1,172 @@
+package ray
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const gib = 1 << 30
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return string(data)
+}
+
+func TestParseStatusFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    *ClusterStatus
+	}{
+		{
+			fixture: "status_legacy.txt",
+			want: &ClusterStatus{
+				Timestamp: "2022-03-14 09:26:53.412345",
+				ActiveNodes: []NodeGroup{
+					{Count: 1, Name: "node_4a1f3c2e9b7d8a6c5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f"},
+					{Count: 2, Name: "ray.worker.default"},
+				},
+				IdleNodes:    []NodeGroup{},
+				PendingNodes: []PendingNode{{NodeType: "ray.worker.default", Status: "1 launching"}},
+				FailedNodes:  []FailedNode{},
+				Resources: map[string]ResourceUsage{
+					ResourceCPU:           {Used: 2, Total: 12},
+					ResourceGPU:           {Used: 0, Total: 1},
+					"accelerator_type:T4": {Used: 0, Total: 1},
+					ResourceMemory:        {Used: 0, Total: 30.5 * gib},
+					ResourceObjectStore:   {Used: 0.5 * gib, Total: 14.25 * gib},
+				},
+				Demands: []ResourceDemand{
+					{Shape: "{'CPU': 1.0}", Count: 4, Kind: "pending tasks/actors"},
+				},
+			},
+		},
+		{
+			fixture: "status_active_idle.txt",
+			want: &ClusterStatus{
+				Timestamp: "2024-02-20 17:41:05.028393",
+				ActiveNodes: []NodeGroup{
+					{Count: 1, Name: "node_8c1e6b1b3f0a4d2e9f7c5a3b1d9e7f5a3c1b9d7e5f3a1c9b7d5e3f1a"},
+					{Count: 2, Name: "ray.worker.gpu"},
+				},
+				IdleNodes: []NodeGroup{{Count: 1, Name: "ray.worker.cpu"}},
+				PendingNodes: []PendingNode{
+					{Address: "10.0.0.5", NodeType: "ray.worker.gpu", Status: "setting up"},
+					{NodeType: "ray.worker.gpu", Status: "launching"},
+				},
+				FailedNodes: []FailedNode{
+					{NodeType: "ray.worker.cpu", Reason: "NodeTerminated", Address: "10.0.0.7"},
+					{NodeType: "ray.worker.gpu", Reason: "RayletUnexpectedlyDied", Address: "10.0.0.8"},
+				},
+				Resources: map[string]ResourceUsage{
+					ResourceCPU:         {Used: 3, Total: 48},
+					ResourceGPU:         {Used: 1, Total: 4},
+					ResourceMemory:      {Used: 0, Total: 120 * gib},
+					ResourceObjectStore: {Used: 1.2 * gib, Total: 50 * gib},
+				},
+				Demands: []ResourceDemand{
+					{Shape: "{'CPU': 1.0}", Count: 10, Kind: "pending tasks/actors"},
+					{Shape: "{'GPU': 1.0, 'CPU': 4.0} * 2 (PACK)", Count: 1, Kind: "pending placement groups"},
+				},
+			},
+		},
+		{
+			fixture: "status_total_usage.txt",
+			want: &ClusterStatus{
+				Timestamp: "2025-06-02 08:12:44.903187",
+				ActiveNodes: []NodeGroup{
+					{Count: 1, Name: "node_f2d4b6a8c0e1f3a5b7c9d1e3f5a7b9c1d3e5f7a9b1c3d5e7f9a1b3c5"},
+				},
+				IdleNodes:    []NodeGroup{},
+				PendingNodes: []PendingNode{},
+				FailedNodes:  []FailedNode{},
+				Resources: map[string]ResourceUsage{
+					ResourceCPU:         {Used: 0, Total: 16},
+					ResourceGPU:         {Used: 0, Total: 1},
+					ResourceMemory:      {Used: 0, Total: 30.5 * gib},
+					ResourceObjectStore: {Used: 0, Total: 15.25 * gib},
+				},
+				Demands: []ResourceDemand{},
+			},
+		},
+		{
+			fixture: "status_unknown_lines.txt",
+			want: &ClusterStatus{
+				Timestamp: "2025-06-02 08:12:44.903187",
+				ActiveNodes: []NodeGroup{
+					{Count: 1, Name: "node_f2d4b6a8c0e1f3a5b7c9d1e3f5a7b9c1d3e5f7a9b1c3d5e7f9a1b3c5"},
+				},
+				IdleNodes:    []NodeGroup{},
+				PendingNodes: []PendingNode{},
+				FailedNodes:  []FailedNode{},
+				Resources: map[string]ResourceUsage{
+					ResourceCPU:    {Used: 0, Total: 16},
+					ResourceMemory: {Used: 0, Total: 30.5 * gib},
+				},
+				Demands:  []ResourceDemand{},
+				Unparsed: []string{"node_without_count", "unlimited CPU", "no shape here"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := ParseStatus(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseStatus: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStatus mismatch\n got: %+v\nwant: %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusUnrecognized(t *testing.T) {
+	for _, output := range []string{
+		"",
+		"Ray cluster is not found at 127.0.0.1:6379\n",
+		"ConnectionError: Could not find any running Ray instance.\n",
+	} {
+		if _, err := ParseStatus(output); err == nil {
+			t.Errorf("ParseStatus(%q) succeeded, want error", output)
+		}
+	}
+}
+
+func TestParseResourceUsage(t *testing.T) {
+	tests := []struct {
+		line    string
+		name    string
+		want    ResourceUsage
+		wantErr bool
+	}{
+		{line: "0.0/16.0 CPU", name: "CPU", want: ResourceUsage{Used: 0, Total: 16}},
+		{line: "512MiB/2.00GiB memory", name: "memory", want: ResourceUsage{Used: 512 << 20, Total: 2 * gib}},
+		{line: "0.00/30.500 GiB memory", name: "memory", want: ResourceUsage{Used: 0, Total: 30.5 * gib}},
+		{line: "1.5GB/3GB object_store_memory", name: "object_store_memory", want: ResourceUsage{Used: 1.5e9, Total: 3e9}},
+		{line: "0.0/1.0 node:10.0.0.4", name: "node:10.0.0.4", want: ResourceUsage{Used: 0, Total: 1}},
+		{line: "CPU 0/16", wantErr: true},
+		{line: "1.2.3/4 CPU", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			name, got, err := parseResourceUsage(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseResourceUsage error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if name != tt.name || got != tt.want {
+				t.Errorf("parseResourceUsage = %q %+v, want %q %+v", name, got, tt.name, tt.want)
+			}
+		})
+	}
+}
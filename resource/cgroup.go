@@ -0,0 +1,331 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hostRoot is the filesystem root /proc and /sys/fs/cgroup are read from
+var hostRoot = "/"
+
+// cgroupV1Unlimited is the smallest value cgroup v1 uses for "no limit";
+// the kernel reports PAGE_COUNTER_MAX rounded to the page size
+const cgroupV1Unlimited = uint64(1) << 62
+
+// memoryStats are the memory figures of the host and of the cgroup the
+// node runs in
+type memoryStats struct {
+	HostTotal     uint64 // MemTotal
+	HostAvailable uint64 // MemAvailable, i.e. free memory plus reclaimable cache
+	Limit         uint64 // cgroup limit, 0 if unlimited
+	WorkingSet    uint64 // cgroup usage minus inactive page cache
+}
+
+// cpuStats are the CPU figures of the host and of the cgroup the node runs in
+type cpuStats struct {
+	HostCount int     // CPUs online on the host
+	Affinity  int     // CPUs the process may run on, e.g. a container's cpuset
+	Quota     float64 // CPUs allowed by the cgroup quota, 0 if unlimited
+}
+
+// Effective returns the total and available memory the node can actually
+// use: the host figures, capped by the cgroup limit when there is one
+func (s memoryStats) Effective() (total, available uint64) {
+	total, available = s.HostTotal, s.HostAvailable
+	if s.Limit == 0 || s.Limit >= total {
+		return total, available
+	}
+
+	total = s.Limit
+	free := uint64(0)
+	if s.WorkingSet < s.Limit {
+		free = s.Limit - s.WorkingSet
+	}
+	if free < available {
+		available = free
+	}
+	return total, available
+}
+
+// Effective returns the number of CPUs the node can use: the host count
+// limited by the CPU affinity and the cgroup quota. A fractional quota is
+// rounded up like the Go runtime does for GOMAXPROCS.
+func (s cpuStats) Effective() int {
+	count := s.HostCount
+	if s.Affinity > 0 && s.Affinity < count {
+		count = s.Affinity
+	}
+	if s.Quota > 0 {
+		if quota := int(math.Ceil(s.Quota)); quota < count {
+			count = quota
+		}
+	}
+	return count
+}
+
+// readMemoryStats reads /proc/meminfo and the memory cgroup under root,
+// which is "/" except when reading fixture trees
+func readMemoryStats(root string) (memoryStats, error) {
+	var stats memoryStats
+
+	meminfo, err := readKeyValues(filepath.Join(root, "proc/meminfo"), ":")
+	if err != nil {
+		return stats, fmt.Errorf("failed to read meminfo: %w", err)
+	}
+	stats.HostTotal = meminfo["MemTotal"] * 1024
+	if available, ok := meminfo["MemAvailable"]; ok {
+		stats.HostAvailable = available * 1024
+	} else {
+		// Kernels before 3.14 lack MemAvailable, approximate it
+		stats.HostAvailable = (meminfo["MemFree"] + meminfo["Buffers"] + meminfo["Cached"]) * 1024
+	}
+
+	cg := detectCgroups(root)
+	if cg.v2 {
+		dir := cg.dir("")
+		stats.Limit = readCgroupLimit(filepath.Join(dir, "memory.max"))
+		usage, _ := readUint(filepath.Join(dir, "memory.current"))
+		memStat, _ := readKeyValues(filepath.Join(dir, "memory.stat"), " ")
+		stats.WorkingSet = subtractFloor(usage, memStat["inactive_file"])
+	} else if dir := cg.dir("memory"); dir != "" {
+		stats.Limit = readCgroupLimit(filepath.Join(dir, "memory.limit_in_bytes"))
+		usage, _ := readUint(filepath.Join(dir, "memory.usage_in_bytes"))
+		memStat, _ := readKeyValues(filepath.Join(dir, "memory.stat"), " ")
+		stats.WorkingSet = subtractFloor(usage, memStat["total_inactive_file"])
+	}
+
+	return stats, nil
+}
+
+// readCPUStats reads the host CPU count and the CPU quota of the cgroup
+// under root. affinity is the number of CPUs the process may run on, which
+// is also the fallback when the host count can't be read.
+func readCPUStats(root string, affinity int) cpuStats {
+	stats := cpuStats{HostCount: readHostCPUCount(root), Affinity: affinity}
+	if stats.HostCount == 0 {
+		stats.HostCount = affinity
+	}
+
+	cg := detectCgroups(root)
+	if cg.v2 {
+		// cpu.max holds "$MAX $PERIOD", where $MAX may be "max"
+		data, err := os.ReadFile(filepath.Join(cg.dir(""), "cpu.max"))
+		if err != nil {
+			return stats
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			return stats
+		}
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+			stats.Quota = quota / period
+		}
+		return stats
+	}
+
+	dir := cg.dir("cpu")
+	if dir == "" {
+		return stats
+	}
+	quota, err1 := readInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+	period, err2 := readInt(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+		stats.Quota = float64(quota) / float64(period)
+	}
+	return stats
+}
+
+// readHostCPUCount returns the number of CPUs online on the host from
+// /sys/devices/system/cpu/online, falling back to the processors listed in
+// /proc/cpuinfo. Unlike runtime.NumCPU, neither is narrowed by a container's
+// cpuset. Returns 0 if neither can be read.
+func readHostCPUCount(root string) int {
+	if data, err := os.ReadFile(filepath.Join(root, "sys/devices/system/cpu/online")); err == nil {
+		if count, err := parseCPUList(strings.TrimSpace(string(data))); err == nil && count > 0 {
+			return count
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "proc/cpuinfo"))
+	if err != nil {
+		return 0
+	}
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if key, _, ok := strings.Cut(scanner.Text(), ":"); ok && strings.TrimSpace(key) == "processor" {
+			count++
+		}
+	}
+	return count
+}
+
+// parseCPUList counts the CPUs in a kernel CPU list such as "0-3,8,10-11"
+func parseCPUList(list string) (int, error) {
+	count := 0
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		lo, err := strconv.Atoi(first)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU list %q", list)
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(last); err != nil || hi < lo {
+				return 0, fmt.Errorf("invalid CPU list %q", list)
+			}
+		}
+		count += hi - lo + 1
+	}
+	return count, nil
+}
+
+// cgroups describes the cgroup hierarchy the process belongs to
+type cgroups struct {
+	root  string
+	v2    bool
+	paths map[string]string // v1 controller (or "" for v2) to cgroup path
+}
+
+// detectCgroups determines the cgroup version and this process's cgroup
+// paths from /proc/self/cgroup
+func detectCgroups(root string) cgroups {
+	cg := cgroups{root: root, paths: make(map[string]string)}
+	_, err := os.Stat(filepath.Join(root, "sys/fs/cgroup/cgroup.controllers"))
+	cg.v2 = err == nil
+
+	data, err := os.ReadFile(filepath.Join(root, "proc/self/cgroup"))
+	if err != nil {
+		return cg
+	}
+	// Lines look like "4:memory:/docker/abc" (v1) or "0::/user.slice" (v2)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			cg.paths[controller] = parts[2]
+		}
+	}
+	return cg
+}
+
+// dir returns the directory of the given v1 controller, or of the v2
+// unified hierarchy when controller is empty. The process's own cgroup is
+// preferred; inside a cgroup namespace that path is not visible and the
+// mount root is used instead. Returns "" if the controller is not mounted.
+func (cg cgroups) dir(controller string) string {
+	base := filepath.Join(cg.root, "sys/fs/cgroup")
+	if !cg.v2 {
+		base = cgroupV1Mount(base, controller)
+		if base == "" {
+			return ""
+		}
+	}
+
+	if path, ok := cg.paths[controller]; ok && path != "/" {
+		candidate := filepath.Join(base, path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return base
+}
+
+// cgroupV1Mount returns the mount point of a v1 controller, which may be
+// co-mounted with others, e.g. "cpu,cpuacct"
+func cgroupV1Mount(base, controller string) string {
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		for _, name := range strings.Split(entry.Name(), ",") {
+			if name == controller {
+				return filepath.Join(base, entry.Name())
+			}
+		}
+	}
+	return ""
+}
+
+// readCgroupLimit reads a memory limit, returning 0 when it is unlimited
+func readCgroupLimit(path string) uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || limit >= cgroupV1Unlimited {
+		return 0
+	}
+	return limit
+}
+
+// readKeyValues parses "key<sep> value [unit]" lines such as /proc/meminfo
+// or memory.stat into a map
+func readKeyValues(path, sep string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), sep)
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			values[strings.TrimSpace(key)] = value
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readUint reads a file holding a single unsigned integer
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// readInt reads a file holding a single signed integer
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// subtractFloor returns a-b, or 0 if b exceeds a
+func subtractFloor(a, b uint64) uint64 {
+	if b > a {
+		return 0
+	}
+	return a - b
+}
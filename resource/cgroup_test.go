@@ -0,0 +1,339 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testMeminfo = `MemTotal:       16384000 kB
+MemFree:         2048000 kB
+MemAvailable:    8192000 kB
+Buffers:          512000 kB
+Cached:          4096000 kB
+`
+
+// writeTree creates files, keyed by slash-separated path, under a temp root.
+// A path ending in "/" creates an empty directory.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestReadMemoryStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  memoryStats
+	}{
+		{
+			name:  "no cgroup",
+			files: map[string]string{"proc/meminfo": testMeminfo},
+			want:  memoryStats{HostTotal: 16384000 << 10, HostAvailable: 8192000 << 10},
+		},
+		{
+			name: "meminfo without MemAvailable",
+			files: map[string]string{
+				"proc/meminfo": "MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 20 kB\nCached: 300 kB\n",
+			},
+			want: memoryStats{HostTotal: 1000 << 10, HostAvailable: 420 << 10},
+		},
+		{
+			name: "v2 limit",
+			files: map[string]string{
+				"proc/meminfo":                            testMeminfo,
+				"proc/self/cgroup":                        "0::/docker/abc\n",
+				"sys/fs/cgroup/cgroup.controllers":        "cpu memory\n",
+				"sys/fs/cgroup/docker/abc/memory.max":     "4294967296\n",
+				"sys/fs/cgroup/docker/abc/memory.current": "1073741824\n",
+				"sys/fs/cgroup/docker/abc/memory.stat":    "anon 536870912\ninactive_file 268435456\n",
+			},
+			want: memoryStats{
+				HostTotal:     16384000 << 10,
+				HostAvailable: 8192000 << 10,
+				Limit:         4 << 30,
+				WorkingSet:    768 << 20,
+			},
+		},
+		{
+			name: "v2 limit max",
+			files: map[string]string{
+				"proc/meminfo":                            testMeminfo,
+				"proc/self/cgroup":                        "0::/docker/abc\n",
+				"sys/fs/cgroup/cgroup.controllers":        "cpu memory\n",
+				"sys/fs/cgroup/docker/abc/memory.max":     "max\n",
+				"sys/fs/cgroup/docker/abc/memory.current": "1073741824\n",
+				"sys/fs/cgroup/docker/abc/memory.stat":    "inactive_file 0\n",
+			},
+			want: memoryStats{
+				HostTotal:     16384000 << 10,
+				HostAvailable: 8192000 << 10,
+				WorkingSet:    1 << 30,
+			},
+		},
+		{
+			name: "v2 cgroup namespace falls back to the mount root",
+			files: map[string]string{
+				"proc/meminfo":                     testMeminfo,
+				"proc/self/cgroup":                 "0::/kubepods/pod1/ctr\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+				"sys/fs/cgroup/memory.max":         "2147483648\n",
+				"sys/fs/cgroup/memory.current":     "536870912\n",
+				"sys/fs/cgroup/memory.stat":        "inactive_file 1073741824\n",
+			},
+			want: memoryStats{
+				HostTotal:     16384000 << 10,
+				HostAvailable: 8192000 << 10,
+				Limit:         2 << 30,
+			},
+		},
+		{
+			name: "v1 limit",
+			files: map[string]string{
+				"proc/meminfo":     testMeminfo,
+				"proc/self/cgroup": "5:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n",
+				"sys/fs/cgroup/memory/docker/abc/memory.limit_in_bytes": "1073741824\n",
+				"sys/fs/cgroup/memory/docker/abc/memory.usage_in_bytes": "805306368\n",
+				"sys/fs/cgroup/memory/docker/abc/memory.stat":           "cache 0\ntotal_inactive_file 268435456\n",
+			},
+			want: memoryStats{
+				HostTotal:     16384000 << 10,
+				HostAvailable: 8192000 << 10,
+				Limit:         1 << 30,
+				WorkingSet:    512 << 20,
+			},
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"proc/meminfo":     testMeminfo,
+				"proc/self/cgroup": "5:memory:/\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+				"sys/fs/cgroup/memory/memory.usage_in_bytes": "1048576\n",
+			},
+			want: memoryStats{
+				HostTotal:     16384000 << 10,
+				HostAvailable: 8192000 << 10,
+				WorkingSet:    1 << 20,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readMemoryStats(writeTree(t, tt.files))
+			if err != nil {
+				t.Fatalf("readMemoryStats: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readMemoryStats = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMemoryStatsMissingMeminfo(t *testing.T) {
+	if _, err := readMemoryStats(t.TempDir()); err == nil {
+		t.Error("readMemoryStats succeeded without /proc/meminfo")
+	}
+}
+
+func TestReadCPUStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		affinity int
+		want     cpuStats
+		wantEff  int
+	}{
+		{
+			name:     "host count from sysfs",
+			files:    map[string]string{"sys/devices/system/cpu/online": "0-15\n"},
+			affinity: 4,
+			want:     cpuStats{HostCount: 16, Affinity: 4},
+			wantEff:  4,
+		},
+		{
+			name:     "host count from cpuinfo",
+			files:    map[string]string{"proc/cpuinfo": "processor\t: 0\nmodel name\t: x\n\nprocessor\t: 1\nmodel name\t: x\n"},
+			affinity: 2,
+			want:     cpuStats{HostCount: 2, Affinity: 2},
+			wantEff:  2,
+		},
+		{
+			name:     "host count falls back to affinity",
+			affinity: 3,
+			want:     cpuStats{HostCount: 3, Affinity: 3},
+			wantEff:  3,
+		},
+		{
+			name: "v2 quota",
+			files: map[string]string{
+				"sys/devices/system/cpu/online":    "0-7\n",
+				"proc/self/cgroup":                 "0::/docker/abc\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+				"sys/fs/cgroup/docker/abc/cpu.max": "150000 100000\n",
+			},
+			affinity: 8,
+			want:     cpuStats{HostCount: 8, Affinity: 8, Quota: 1.5},
+			wantEff:  2,
+		},
+		{
+			name: "v2 quota max",
+			files: map[string]string{
+				"sys/devices/system/cpu/online":    "0-7\n",
+				"proc/self/cgroup":                 "0::/docker/abc\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+				"sys/fs/cgroup/docker/abc/cpu.max": "max 100000\n",
+			},
+			affinity: 8,
+			want:     cpuStats{HostCount: 8, Affinity: 8},
+			wantEff:  8,
+		},
+		{
+			name: "v2 cgroup namespace falls back to the mount root",
+			files: map[string]string{
+				"sys/devices/system/cpu/online":    "0-7\n",
+				"proc/self/cgroup":                 "0::/kubepods/pod1/ctr\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+				"sys/fs/cgroup/cpu.max":            "200000 100000\n",
+			},
+			affinity: 8,
+			want:     cpuStats{HostCount: 8, Affinity: 8, Quota: 2},
+			wantEff:  2,
+		},
+		{
+			name: "v1 co-mounted cpu,cpuacct",
+			files: map[string]string{
+				"sys/devices/system/cpu/online":                          "0-3,8-11\n",
+				"proc/self/cgroup":                                       "5:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n",
+				"sys/fs/cgroup/memory/":                                  "",
+				"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "300000\n",
+				"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+			},
+			affinity: 8,
+			want:     cpuStats{HostCount: 8, Affinity: 8, Quota: 3},
+			wantEff:  3,
+		},
+		{
+			name: "v1 quota unlimited",
+			files: map[string]string{
+				"sys/devices/system/cpu/online":               "0-3\n",
+				"proc/self/cgroup":                            "3:cpu,cpuacct:/\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "-1\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+			},
+			affinity: 4,
+			want:     cpuStats{HostCount: 4, Affinity: 4},
+			wantEff:  4,
+		},
+		{
+			name: "v1 cpu controller not mounted",
+			files: map[string]string{
+				"sys/devices/system/cpu/online": "0-3\n",
+				"proc/self/cgroup":              "5:memory:/docker/abc\n",
+				"sys/fs/cgroup/memory/":         "",
+			},
+			affinity: 4,
+			want:     cpuStats{HostCount: 4, Affinity: 4},
+			wantEff:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readCPUStats(writeTree(t, tt.files), tt.affinity)
+			if got != tt.want {
+				t.Errorf("readCPUStats = %+v, want %+v", got, tt.want)
+			}
+			if eff := got.Effective(); eff != tt.wantEff {
+				t.Errorf("Effective = %d, want %d", eff, tt.wantEff)
+			}
+		})
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    int
+		wantErr bool
+	}{
+		{list: "0", want: 1},
+		{list: "0-63", want: 64},
+		{list: "0-3,8,10-11", want: 7},
+		{list: "", wantErr: true},
+		{list: "3-1", wantErr: true},
+		{list: "0-x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUList(tt.list)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseCPUList(%q) = %d, %v; want %d, error %v", tt.list, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMemoryStatsEffective(t *testing.T) {
+	tests := []struct {
+		name          string
+		stats         memoryStats
+		wantTotal     uint64
+		wantAvailable uint64
+	}{
+		{
+			name:          "no limit",
+			stats:         memoryStats{HostTotal: 16, HostAvailable: 8},
+			wantTotal:     16,
+			wantAvailable: 8,
+		},
+		{
+			name:          "limit above host",
+			stats:         memoryStats{HostTotal: 16, HostAvailable: 8, Limit: 32, WorkingSet: 4},
+			wantTotal:     16,
+			wantAvailable: 8,
+		},
+		{
+			name:          "limit caps available",
+			stats:         memoryStats{HostTotal: 16, HostAvailable: 8, Limit: 4, WorkingSet: 1},
+			wantTotal:     4,
+			wantAvailable: 3,
+		},
+		{
+			name:          "host available below limit headroom",
+			stats:         memoryStats{HostTotal: 16, HostAvailable: 2, Limit: 8, WorkingSet: 1},
+			wantTotal:     8,
+			wantAvailable: 2,
+		},
+		{
+			name:          "working set over limit",
+			stats:         memoryStats{HostTotal: 16, HostAvailable: 8, Limit: 4, WorkingSet: 5},
+			wantTotal:     4,
+			wantAvailable: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, available := tt.stats.Effective()
+			if total != tt.wantTotal || available != tt.wantAvailable {
+				t.Errorf("Effective = %d, %d; want %d, %d", total, available, tt.wantTotal, tt.wantAvailable)
+			}
+		})
+	}
+}
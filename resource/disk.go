@@ -0,0 +1,170 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DiskVolume is the usage of the filesystem holding one configured path.
+// When the path cannot be read, Error says why and the amounts are zero.
+type DiskVolume struct {
+	Name        string `json:"name,omitempty"`
+	Path        string `json:"path"`
+	MountPoint  string `json:"mount_point,omitempty"`
+	Device      string `json:"device,omitempty"`
+	FSType      string `json:"fs_type,omitempty"`
+	Total       uint64 `json:"total"`     // Bytes
+	Free        uint64 `json:"free"`      // Bytes, including blocks reserved for root
+	Available   uint64 `json:"available"` // Bytes usable by unprivileged users
+	InodesTotal uint64 `json:"inodes_total"`
+	InodesFree  uint64 `json:"inodes_free"`
+	Error       string `json:"error,omitempty"`
+}
+
+// mountInfo is an entry of /proc/self/mounts
+type mountInfo struct {
+	Device     string
+	MountPoint string
+	FSType     string
+}
+
+// readDiskVolumes reports the volumes holding each of the given paths,
+// written as "path" or "name=path"
+func readDiskVolumes(root string, paths []string) []DiskVolume {
+	mounts, _ := readMounts(filepath.Join(root, "proc/self/mounts"))
+
+	volumes := make([]DiskVolume, 0, len(paths))
+	for _, entry := range paths {
+		var volume DiskVolume
+		if name, path, ok := strings.Cut(entry, "="); ok {
+			volume.Name, volume.Path = name, path
+		} else {
+			volume.Path = entry
+		}
+
+		resolved, err := filepath.Abs(volume.Path)
+		if err == nil {
+			resolved, err = filepath.EvalSymlinks(resolved)
+		}
+		if err != nil {
+			volume.Error = err.Error()
+			volumes = append(volumes, volume)
+			continue
+		}
+
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(resolved, &stat); err != nil {
+			volume.Error = "statfs " + resolved + ": " + err.Error()
+			volumes = append(volumes, volume)
+			continue
+		}
+		volume.Total = stat.Blocks * uint64(stat.Bsize)
+		volume.Free = stat.Bfree * uint64(stat.Bsize)
+		volume.Available = stat.Bavail * uint64(stat.Bsize)
+		volume.InodesTotal = stat.Files
+		volume.InodesFree = stat.Ffree
+
+		if mount, ok := findMount(mounts, resolved); ok {
+			volume.MountPoint = mount.MountPoint
+			volume.Device = mount.Device
+			volume.FSType = mount.FSType
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// sumDiskVolumes returns the total and free bytes of the readable volumes,
+// counting a filesystem shared by several paths once
+func sumDiskVolumes(volumes []DiskVolume) (total, free uint64) {
+	seen := make(map[string]bool)
+	for _, volume := range volumes {
+		if volume.Error != "" {
+			continue
+		}
+		key := volume.MountPoint
+		if key == "" {
+			key = volume.Path
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		total += volume.Total
+		free += volume.Free
+	}
+	return total, free
+}
+
+// readMounts parses a mounts file in fstab format
+func readMounts(path string) ([]mountInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mounts []mountInfo
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		mounts = append(mounts, mountInfo{
+			Device:     unescapeMountField(fields[0]),
+			MountPoint: unescapeMountField(fields[1]),
+			FSType:     fields[2],
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// findMount returns the mount holding path: the one with the longest mount
+// point that is a prefix of it. Later entries win ties, as they are mounted
+// on top of earlier ones.
+func findMount(mounts []mountInfo, path string) (mountInfo, bool) {
+	var best mountInfo
+	found := false
+	for _, mount := range mounts {
+		if !pathWithin(path, mount.MountPoint) {
+			continue
+		}
+		if !found || len(mount.MountPoint) >= len(best.MountPoint) {
+			best, found = mount, true
+		}
+	}
+	return best, found
+}
+
+// pathWithin reports whether path is dir or below it
+func pathWithin(path, dir string) bool {
+	if dir == "/" || path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// unescapeMountField decodes the octal escapes the kernel uses for spaces,
+// tabs, newlines and backslashes in mount paths, e.g. "\040"
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if v, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,219 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindMount(t *testing.T) {
+	mounts := []mountInfo{
+		{Device: "/dev/sda1", MountPoint: "/", FSType: "ext4"},
+		{Device: "/dev/sdb1", MountPoint: "/data", FSType: "ext4"},
+		{Device: "/dev/sdc1", MountPoint: "/data/ray", FSType: "xfs"},
+		{Device: "/dev/sdd1", MountPoint: "/data2", FSType: "ext4"},
+		{Device: "tmpfs", MountPoint: "/data", FSType: "tmpfs"}, // Mounted over /dev/sdb1
+	}
+
+	tests := []struct {
+		name   string
+		mounts []mountInfo
+		path   string
+		want   string // Device, empty if none
+	}{
+		{name: "longest mount point wins", mounts: mounts, path: "/data/ray/session", want: "/dev/sdc1"},
+		{name: "mount point itself", mounts: mounts, path: "/data/ray", want: "/dev/sdc1"},
+		{name: "later mount over the same point wins", mounts: mounts, path: "/data/models", want: "tmpfs"},
+		{name: "prefix without a separator doesn't match", mounts: mounts, path: "/data2/x", want: "/dev/sdd1"},
+		{name: "root holds the rest", mounts: mounts, path: "/home/ray", want: "/dev/sda1"},
+		{name: "trailing slash in mount point", mounts: []mountInfo{{Device: "nfs", MountPoint: "/mnt/"}}, path: "/mnt/x", want: "nfs"},
+		{name: "no mounts", path: "/data"},
+		{name: "outside every mount", mounts: mounts[1:], path: "/home/ray"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := findMount(tt.mounts, tt.path)
+			if ok != (tt.want != "") || got.Device != tt.want {
+				t.Errorf("findMount(%q) = %+v, %v, want %q", tt.path, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeMountField(t *testing.T) {
+	tests := []struct {
+		field, want string
+	}{
+		{`/mnt/data`, "/mnt/data"},
+		{`/mnt/my\040disk`, "/mnt/my disk"},
+		{`/mnt/a\011b\012c`, "/mnt/a\tb\nc"},
+		{`/mnt/back\134slash`, `/mnt/back\slash`},
+		{`/mnt/not\9octal`, `/mnt/not\9octal`},
+		{`/mnt/short\04`, `/mnt/short\04`},
+		{`/mnt/end\`, `/mnt/end\`},
+	}
+	for _, tt := range tests {
+		if got := unescapeMountField(tt.field); got != tt.want {
+			t.Errorf("unescapeMountField(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestReadDiskVolumes(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"data/ray/":   "",
+		"with space/": "",
+	})
+	// Mount points are absolute, so the fixture mounts name real paths
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "data/ray"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	escapedRoot := strings.ReplaceAll(root, " ", `\040`)
+	mounts := strings.Join([]string{
+		"/dev/sda1 / ext4 rw 0 0",
+		"/dev/sdb1 " + escapedRoot + "/data ext4 rw 0 0",
+		"/dev/sdc1 " + escapedRoot + "/data/ray xfs rw 0 0",
+		`/dev/my\040disk ` + escapedRoot + `/with\040space ext4 rw 0 0`,
+		"short line",
+	}, "\n") + "\n"
+	if err := os.MkdirAll(filepath.Join(root, "proc/self"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "proc/self/mounts"), []byte(mounts), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		entry      string
+		wantName   string
+		wantPath   string
+		wantMount  string
+		wantDevice string
+		wantFSType string
+		wantError  string
+	}{
+		{
+			entry:    "data=" + root + "/data",
+			wantName: "data", wantPath: root + "/data",
+			wantMount: root + "/data", wantDevice: "/dev/sdb1", wantFSType: "ext4",
+		},
+		{
+			entry:    "ray_temp=" + root + "/data/ray",
+			wantName: "ray_temp", wantPath: root + "/data/ray",
+			wantMount: root + "/data/ray", wantDevice: "/dev/sdc1", wantFSType: "xfs",
+		},
+		{
+			entry:     root + "/with space",
+			wantPath:  root + "/with space",
+			wantMount: root + "/with space", wantDevice: "/dev/my disk", wantFSType: "ext4",
+		},
+		{
+			entry:    "linked=" + root + "/link",
+			wantName: "linked", wantPath: root + "/link",
+			wantMount: root + "/data/ray", wantDevice: "/dev/sdc1", wantFSType: "xfs",
+		},
+		{
+			entry:    "missing=" + root + "/nope",
+			wantName: "missing", wantPath: root + "/nope",
+			wantError: "no such file or directory",
+		},
+	}
+
+	entries := make([]string, len(tests))
+	for i, tt := range tests {
+		entries[i] = tt.entry
+	}
+	volumes := readDiskVolumes(root, entries)
+	if len(volumes) != len(tests) {
+		t.Fatalf("readDiskVolumes returned %d volumes, want %d", len(volumes), len(tests))
+	}
+
+	for i, tt := range tests {
+		v := volumes[i]
+		if v.Name != tt.wantName || v.Path != tt.wantPath {
+			t.Errorf("%s: name, path = %q, %q, want %q, %q", tt.entry, v.Name, v.Path, tt.wantName, tt.wantPath)
+		}
+		if tt.wantError != "" {
+			if !strings.Contains(v.Error, tt.wantError) || v.Total != 0 || v.MountPoint != "" {
+				t.Errorf("%s: volume = %+v, want only an error containing %q", tt.entry, v, tt.wantError)
+			}
+			continue
+		}
+		if v.Error != "" {
+			t.Errorf("%s: error %q", tt.entry, v.Error)
+		}
+		if v.MountPoint != tt.wantMount || v.Device != tt.wantDevice || v.FSType != tt.wantFSType {
+			t.Errorf("%s: mount = %q %q %q, want %q %q %q", tt.entry,
+				v.MountPoint, v.Device, v.FSType, tt.wantMount, tt.wantDevice, tt.wantFSType)
+		}
+		if v.Total == 0 || v.Available > v.Total || v.InodesFree > v.InodesTotal {
+			t.Errorf("%s: implausible usage %+v", tt.entry, v)
+		}
+	}
+}
+
+func TestReadDiskVolumesWithoutMounts(t *testing.T) {
+	root := t.TempDir()
+	volumes := readDiskVolumes(root, []string{root})
+	if len(volumes) != 1 || volumes[0].Error != "" || volumes[0].Total == 0 || volumes[0].MountPoint != "" {
+		t.Errorf("readDiskVolumes without a mounts file = %+v, want usage without mount details", volumes)
+	}
+}
+
+func TestSumDiskVolumes(t *testing.T) {
+	tests := []struct {
+		name      string
+		volumes   []DiskVolume
+		wantTotal uint64
+		wantFree  uint64
+	}{
+		{
+			name: "separate filesystems",
+			volumes: []DiskVolume{
+				{Path: "/data", MountPoint: "/data", Total: 100, Free: 40},
+				{Path: "/tmp/ray", MountPoint: "/", Total: 50, Free: 10},
+			},
+			wantTotal: 150, wantFree: 50,
+		},
+		{
+			name: "shared filesystem counted once",
+			volumes: []DiskVolume{
+				{Path: "/data", MountPoint: "/", Total: 100, Free: 40},
+				{Path: "/tmp/ray", MountPoint: "/", Total: 100, Free: 40},
+				{Path: "/var/lib", MountPoint: "/", Total: 100, Free: 40},
+			},
+			wantTotal: 100, wantFree: 40,
+		},
+		{
+			name: "unknown mounts keyed by path",
+			volumes: []DiskVolume{
+				{Path: "/data", Total: 100, Free: 40},
+				{Path: "/data", Total: 100, Free: 40},
+				{Path: "/models", Total: 30, Free: 5},
+			},
+			wantTotal: 130, wantFree: 45,
+		},
+		{
+			name: "unreadable volumes skipped",
+			volumes: []DiskVolume{
+				{Path: "/data", MountPoint: "/data", Total: 100, Free: 40},
+				{Path: "/missing", Error: "no such file or directory"},
+			},
+			wantTotal: 100, wantFree: 40,
+		},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, free := sumDiskVolumes(tt.volumes)
+			if total != tt.wantTotal || free != tt.wantFree {
+				t.Errorf("sumDiskVolumes = %d, %d, want %d, %d", total, free, tt.wantTotal, tt.wantFree)
+			}
+		})
+	}
+}
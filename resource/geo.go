@@ -0,0 +1,202 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// maxGeoResponseSize bounds the body read from an online lookup
+const maxGeoResponseSize = 64 << 10
+
+// GeoProvider determines the node's location
+type GeoProvider interface {
+	// Name returns the provider name, one of the config.GeoProvider constants
+	Name() string
+	Lookup() (*GeoLocation, error)
+}
+
+// NewGeoProvider returns the provider selected by cfg.GeoProvider, or nil if
+// geolocation is disabled. client is used for online lookups.
+func NewGeoProvider(cfg *config.Config, client *http.Client) (GeoProvider, error) {
+	switch cfg.GeoProvider {
+	case config.GeoProviderHTTP, "":
+		return &HTTPGeoProvider{URL: cfg.GeoHTTPURL, Client: client}, nil
+	case config.GeoProviderMMDB:
+		return NewMMDBGeoProvider(cfg.GeoMMDBPath, cfg.GeoIP)
+	case config.GeoProviderStatic:
+		geo := &GeoLocation{
+			IP:      cfg.GeoIP,
+			City:    cfg.GeoCity,
+			Region:  cfg.GeoRegion,
+			Country: cfg.GeoCountry,
+		}
+		if cfg.GeoLatitude != nil && cfg.GeoLongitude != nil {
+			geo.Latitude = *cfg.GeoLatitude
+			geo.Longitude = *cfg.GeoLongitude
+		}
+		return StaticGeoProvider{Location: *geo}, nil
+	case config.GeoProviderNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown geo provider %q", cfg.GeoProvider)
+	}
+}
+
+// HTTPGeoProvider looks up the node's public IP with an online service
+// answering in the ipapi.co JSON format
+type HTTPGeoProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+// ipapiResponse is the subset of the ipapi.co response the node reports.
+// Pointers tell missing fields apart from zero values.
+type ipapiResponse struct {
+	IP        *string  `json:"ip"`
+	City      string   `json:"city"`
+	Region    string   `json:"region"`
+	Country   *string  `json:"country_name"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Error     bool     `json:"error"`
+	Reason    string   `json:"reason"`
+}
+
+// Name returns the provider name
+func (p *HTTPGeoProvider) Name() string { return config.GeoProviderHTTP }
+
+// Lookup queries the service, rejecting error responses and responses
+// missing the IP, country or coordinates
+func (p *HTTPGeoProvider) Lookup() (*GeoLocation, error) {
+	resp, err := p.Client.Get(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error getting geo location: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGeoResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("error reading geo response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("geo lookup failed with status: %s", resp.Status)
+	}
+
+	return parseIPAPIResponse(body)
+}
+
+// parseIPAPIResponse strictly decodes an ipapi.co response
+func parseIPAPIResponse(body []byte) (*GeoLocation, error) {
+	var result ipapiResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("error parsing geo response: %w", err)
+	}
+	if result.Error {
+		return nil, fmt.Errorf("geo lookup failed: %s", result.Reason)
+	}
+	if result.IP == nil || result.Country == nil || result.Latitude == nil || result.Longitude == nil {
+		return nil, fmt.Errorf("incomplete geo response: ip, country_name, latitude and longitude are required")
+	}
+
+	return &GeoLocation{
+		IP:        *result.IP,
+		City:      result.City,
+		Region:    result.Region,
+		Country:   *result.Country,
+		Latitude:  *result.Latitude,
+		Longitude: *result.Longitude,
+	}, nil
+}
+
+// MMDBGeoProvider looks up the node's IP in a local MaxMind/GeoLite2 City
+// database, for nodes without internet access
+type MMDBGeoProvider struct {
+	reader *geoip2.Reader
+	ip     string
+}
+
+// NewMMDBGeoProvider opens the database at path. ip is the address to look
+// up; when empty, the local address of the default route is used, which is
+// only useful if the node has a public IP.
+func NewMMDBGeoProvider(path, ip string) (*MMDBGeoProvider, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geo database %s: %w", path, err)
+	}
+	return &MMDBGeoProvider{reader: reader, ip: ip}, nil
+}
+
+// Name returns the provider name
+func (p *MMDBGeoProvider) Name() string { return config.GeoProviderMMDB }
+
+// Lookup finds the node's IP in the database
+func (p *MMDBGeoProvider) Lookup() (*GeoLocation, error) {
+	ipStr := p.ip
+	if ipStr == "" {
+		var err error
+		if ipStr, err = outboundIP(); err != nil {
+			return nil, err
+		}
+	}
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", ipStr)
+	}
+
+	record, err := p.reader.City(ip)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up %s: %w", ipStr, err)
+	}
+	if record.Country.IsoCode == "" && record.Location.Latitude == 0 && record.Location.Longitude == 0 {
+		return nil, fmt.Errorf("no location found for %s", ipStr)
+	}
+
+	geo := &GeoLocation{
+		IP:        ipStr,
+		City:      record.City.Names["en"],
+		Country:   record.Country.Names["en"],
+		Latitude:  record.Location.Latitude,
+		Longitude: record.Location.Longitude,
+	}
+	if len(record.Subdivisions) > 0 {
+		geo.Region = record.Subdivisions[0].Names["en"]
+	}
+	return geo, nil
+}
+
+// StaticGeoProvider reports a location configured by the operator
+type StaticGeoProvider struct {
+	Location GeoLocation
+}
+
+// Name returns the provider name
+func (p StaticGeoProvider) Name() string { return config.GeoProviderStatic }
+
+// Lookup returns the configured location
+func (p StaticGeoProvider) Lookup() (*GeoLocation, error) {
+	geo := p.Location
+	return &geo, nil
+}
+
+// outboundIP returns the local address used to reach the internet. No
+// packets are sent: connecting a UDP socket only selects the route.
+func outboundIP() (string, error) {
+	conn, err := net.Dial("udp", "8.8.8.8:53")
+	if err != nil {
+		return "", fmt.Errorf("failed to determine outbound IP: %w", err)
+	}
+	defer conn.Close()
+
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return "", errors.New("failed to determine outbound IP")
+	}
+	return addr.IP.String(), nil
+}
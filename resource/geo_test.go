@@ -0,0 +1,89 @@
+package resource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIPAPIResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    *GeoLocation
+		wantErr string
+	}{
+		{
+			name: "complete",
+			body: `{"ip": "203.0.113.7", "city": "Hanoi", "region": "Hanoi", "country_name": "Vietnam", "latitude": 21.0292, "longitude": 105.8526}`,
+			want: &GeoLocation{IP: "203.0.113.7", City: "Hanoi", Region: "Hanoi", Country: "Vietnam", Latitude: 21.0292, Longitude: 105.8526},
+		},
+		{
+			name: "optional fields missing",
+			body: `{"ip": "203.0.113.7", "country_name": "Vietnam", "latitude": 0, "longitude": 0}`,
+			want: &GeoLocation{IP: "203.0.113.7", Country: "Vietnam"},
+		},
+		{
+			name:    "error response",
+			body:    `{"ip": "203.0.113.7", "error": true, "reason": "RateLimited"}`,
+			wantErr: "geo lookup failed: RateLimited",
+		},
+		{
+			name:    "missing ip",
+			body:    `{"country_name": "Vietnam", "latitude": 21.03, "longitude": 105.85}`,
+			wantErr: "incomplete geo response",
+		},
+		{
+			name:    "missing country",
+			body:    `{"ip": "203.0.113.7", "latitude": 21.03, "longitude": 105.85}`,
+			wantErr: "incomplete geo response",
+		},
+		{
+			name:    "missing coordinates",
+			body:    `{"ip": "203.0.113.7", "country_name": "Vietnam", "latitude": 21.03}`,
+			wantErr: "incomplete geo response",
+		},
+		{
+			name:    "ip-api.com format",
+			body:    `{"status": "success", "query": "203.0.113.7", "country": "Vietnam", "lat": 21.03, "lon": 105.85}`,
+			wantErr: "incomplete geo response",
+		},
+		{
+			name:    "not json",
+			body:    `<html>Too Many Requests</html>`,
+			wantErr: "error parsing geo response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIPAPIResponse([]byte(tt.body))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseIPAPIResponse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPGeoProviderStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"ip": "203.0.113.7", "country_name": "Vietnam", "latitude": 21.03, "longitude": 105.85}`))
+	}))
+	defer srv.Close()
+
+	p := &HTTPGeoProvider{URL: srv.URL, Client: srv.Client()}
+	if _, err := p.Lookup(); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("Lookup error = %v, want the 429 status", err)
+	}
+}
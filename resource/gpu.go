@@ -0,0 +1,124 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// gpuCommandTimeout bounds each GPU tool invocation, which can hang when a
+// driver is wedged
+const gpuCommandTimeout = 10 * time.Second
+
+// GPUDevice describes a single accelerator. Amounts the driver does not
+// report are left at zero.
+type GPUDevice struct {
+	Index         int     `json:"index"`
+	UUID          string  `json:"uuid,omitempty"`
+	Model         string  `json:"model"`
+	MemoryTotal   uint64  `json:"memory_total"`          // MiB
+	MemoryFree    uint64  `json:"memory_free"`           // MiB
+	MemoryUsed    uint64  `json:"memory_used"`           // MiB
+	Utilization   float64 `json:"utilization"`           // Percent
+	Temperature   float64 `json:"temperature,omitempty"` // Celsius
+	PowerDraw     float64 `json:"power_draw,omitempty"`  // Watts
+	DriverVersion string  `json:"driver_version,omitempty"`
+	CUDAVersion   string  `json:"cuda_version,omitempty"`
+	PCIBusID      string  `json:"pci_bus_id,omitempty"`
+}
+
+// GPUProbe detects the accelerators of one vendor using its management tool
+type GPUProbe interface {
+	// Vendor returns the vendor name, one of the config.GPUVendor constants
+	Vendor() string
+	// Devices lists the vendor's devices, failing if the tool is missing
+	Devices() ([]GPUDevice, error)
+}
+
+// commandFunc runs a tool and returns its standard output
+type commandFunc func(name string, args ...string) ([]byte, error)
+
+// NewGPUProbes returns the probes for a GPU_VENDOR setting, in the order
+// they should be tried. "auto" tries every vendor, "none" disables detection.
+func NewGPUProbes(vendor string) ([]GPUProbe, error) {
+	nvidia := nvidiaProbe{run: runGPUCommand}
+	amd := rocmProbe{run: runGPUCommand}
+	intel := xpuProbe{run: runGPUCommand}
+
+	switch vendor {
+	case config.GPUVendorAuto, "":
+		return []GPUProbe{nvidia, amd, intel}, nil
+	case config.GPUVendorNvidia:
+		return []GPUProbe{nvidia}, nil
+	case config.GPUVendorAMD:
+		return []GPUProbe{amd}, nil
+	case config.GPUVendorIntel:
+		return []GPUProbe{intel}, nil
+	case config.GPUVendorNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown GPU vendor %q", vendor)
+	}
+}
+
+// detectGPUs returns the devices of the first probe that finds any, along
+// with its vendor
+func detectGPUs(probes []GPUProbe) (string, []GPUDevice) {
+	for _, probe := range probes {
+		devices, err := probe.Devices()
+		if err == nil && len(devices) > 0 {
+			return probe.Vendor(), devices
+		}
+	}
+	return "", []GPUDevice{}
+}
+
+// summarizeGPUs fills the aggregate GPU fields of r from its devices: the
+// device count, the first device's model and the summed memory
+func summarizeGPUs(r *Resources) {
+	r.GPUCount = len(r.GPUs)
+	r.GPUModel = ""
+	r.GPUMemoryTotal = 0
+	r.GPUMemoryFree = 0
+	for i, device := range r.GPUs {
+		if i == 0 {
+			r.GPUModel = device.Model
+		}
+		r.GPUMemoryTotal += device.MemoryTotal
+		r.GPUMemoryFree += device.MemoryFree
+	}
+}
+
+// runGPUCommand runs a GPU tool with gpuCommandTimeout
+func runGPUCommand(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gpuCommandTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+// parseUintOrZero parses an unsigned integer, returning 0 for empty or
+// malformed values
+func parseUintOrZero(s string) uint64 {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		// Some drivers report whole amounts with a fraction, e.g. "81920.0"
+		if f, ferr := strconv.ParseFloat(s, 64); ferr == nil && f >= 0 {
+			return uint64(f)
+		}
+		return 0
+	}
+	return v
+}
+
+// parseFloatOrZero parses a float, returning 0 for empty or malformed values
+func parseFloatOrZero(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
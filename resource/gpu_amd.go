@@ -0,0 +1,94 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// rocmProbe detects AMD GPUs using rocm-smi
+type rocmProbe struct {
+	run commandFunc
+}
+
+// Vendor returns the vendor name
+func (p rocmProbe) Vendor() string { return config.GPUVendorAMD }
+
+// Devices lists the AMD GPUs
+func (p rocmProbe) Devices() ([]GPUDevice, error) {
+	out, err := p.run("rocm-smi",
+		"--showproductname", "--showmeminfo", "vram", "--showuse", "--showtemp",
+		"--showpower", "--showbus", "--showuniqueid", "--showdriverversion", "--json")
+	// rocm-smi exits non-zero when a metric is unsupported but still prints
+	// the rest, so only give up if there is no output at all
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return parseROCmJSON(out)
+}
+
+// parseROCmJSON parses the output of rocm-smi --json, an object keyed by
+// "card0", "card1", ... plus a "system" entry, with string values
+func parseROCmJSON(output []byte) ([]GPUDevice, error) {
+	var entries map[string]map[string]interface{}
+	if err := json.Unmarshal(output, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse rocm-smi output: %w", err)
+	}
+
+	driverVersion := lookupField(entries["system"], "Driver version")
+
+	devices := []GPUDevice{}
+	for key, fields := range entries {
+		if !strings.HasPrefix(key, "card") {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(key, "card"))
+		if err != nil {
+			continue
+		}
+
+		device := GPUDevice{
+			Index:         index,
+			UUID:          lookupField(fields, "Unique ID"),
+			Model:         lookupField(fields, "Card series", "Card SKU", "Card model"),
+			MemoryTotal:   parseUintOrZero(lookupField(fields, "VRAM Total Memory (B)")) >> 20,
+			MemoryUsed:    parseUintOrZero(lookupField(fields, "VRAM Total Used Memory (B)")) >> 20,
+			Utilization:   parseFloatOrZero(lookupField(fields, "GPU use (%)")),
+			Temperature:   parseFloatOrZero(lookupField(fields, "Temperature (Sensor edge) (C)", "Temperature (Sensor junction) (C)")),
+			PowerDraw:     parseFloatOrZero(lookupField(fields, "Average Graphics Package Power (W)", "Current Socket Graphics Package Power (W)")),
+			DriverVersion: lookupField(fields, "Driver version"),
+			PCIBusID:      lookupField(fields, "PCI Bus"),
+		}
+		if device.DriverVersion == "" {
+			device.DriverVersion = driverVersion
+		}
+		device.MemoryFree = subtractFloor(device.MemoryTotal, device.MemoryUsed)
+		devices = append(devices, device)
+	}
+
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Index < devices[j].Index })
+	return devices, nil
+}
+
+// lookupField returns the first of the named fields present in a JSON
+// object, matching names case-insensitively since their capitalization
+// differs between tool versions. Unsupported values ("N/A") are blanked.
+func lookupField(fields map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		for key, value := range fields {
+			if !strings.EqualFold(key, name) || value == nil {
+				continue
+			}
+			s := strings.TrimSpace(fmt.Sprint(value))
+			if s == "" || strings.EqualFold(s, "N/A") {
+				continue
+			}
+			return s
+		}
+	}
+	return ""
+}
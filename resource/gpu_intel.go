@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// xpuProbe detects Intel GPUs using xpu-smi
+type xpuProbe struct {
+	run commandFunc
+}
+
+// xpuDeviceList is the output of xpu-smi discovery -j
+type xpuDeviceList struct {
+	Devices []struct {
+		DeviceID   int    `json:"device_id"`
+		DeviceName string `json:"device_name"`
+		DeviceType string `json:"device_type"`
+		UUID       string `json:"uuid"`
+		PCIAddress string `json:"pci_bdf_address"`
+	} `json:"device_list"`
+}
+
+// xpuStats is the output of xpu-smi stats -d <id> -j
+type xpuStats struct {
+	DeviceLevel []struct {
+		MetricsType string  `json:"metrics_type"`
+		Value       float64 `json:"value"`
+	} `json:"device_level"`
+}
+
+// Vendor returns the vendor name
+func (p xpuProbe) Vendor() string { return config.GPUVendorIntel }
+
+// Devices lists the Intel GPUs. The device list comes from discovery;
+// memory size, driver version and live stats need one call per device and
+// are best-effort.
+func (p xpuProbe) Devices() ([]GPUDevice, error) {
+	out, err := p.run("xpu-smi", "discovery", "-j")
+	if err != nil {
+		return nil, err
+	}
+	devices, err := parseXPUDiscovery(out)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range devices {
+		id := strconv.Itoa(devices[i].Index)
+		if out, err := p.run("xpu-smi", "discovery", "-d", id, "-j"); err == nil {
+			applyXPUDeviceDetails(&devices[i], out)
+		}
+		if out, err := p.run("xpu-smi", "stats", "-d", id, "-j"); err == nil {
+			applyXPUStats(&devices[i], out)
+		}
+	}
+	return devices, nil
+}
+
+// parseXPUDiscovery parses the device list of xpu-smi discovery -j
+func parseXPUDiscovery(output []byte) ([]GPUDevice, error) {
+	var list xpuDeviceList
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse xpu-smi output: %w", err)
+	}
+
+	devices := []GPUDevice{}
+	for _, d := range list.Devices {
+		if d.DeviceType != "" && d.DeviceType != "GPU" {
+			continue
+		}
+		devices = append(devices, GPUDevice{
+			Index:    d.DeviceID,
+			UUID:     d.UUID,
+			Model:    d.DeviceName,
+			PCIBusID: d.PCIAddress,
+		})
+	}
+	return devices, nil
+}
+
+// applyXPUDeviceDetails adds the memory size and driver version from
+// xpu-smi discovery -d <id> -j, whose values are strings
+func applyXPUDeviceDetails(device *GPUDevice, output []byte) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(output, &fields); err != nil {
+		return
+	}
+	if total := parseUintOrZero(lookupField(fields, "memory_physical_size_byte")) >> 20; total > 0 {
+		device.MemoryTotal = total
+		device.MemoryFree = subtractFloor(device.MemoryTotal, device.MemoryUsed)
+	}
+	if version := lookupField(fields, "driver_version"); version != "" {
+		device.DriverVersion = version
+	}
+}
+
+// applyXPUStats adds utilization, power, temperature and used memory from
+// xpu-smi stats -d <id> -j
+func applyXPUStats(device *GPUDevice, output []byte) {
+	var stats xpuStats
+	if err := json.Unmarshal(output, &stats); err != nil {
+		return
+	}
+	for _, metric := range stats.DeviceLevel {
+		switch metric.MetricsType {
+		case "XPUM_STATS_GPU_UTILIZATION":
+			device.Utilization = metric.Value
+		case "XPUM_STATS_POWER":
+			device.PowerDraw = metric.Value
+		case "XPUM_STATS_GPU_CORE_TEMPERATURE":
+			device.Temperature = metric.Value
+		case "XPUM_STATS_MEMORY_USED": // MiB
+			device.MemoryUsed = uint64(metric.Value)
+		}
+	}
+	device.MemoryFree = subtractFloor(device.MemoryTotal, device.MemoryUsed)
+}
@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// nvidiaQueryFields are the nvidia-smi --query-gpu fields, in the column
+// order parseNvidiaCSV expects
+var nvidiaQueryFields = []string{
+	"index",
+	"uuid",
+	"name",
+	"memory.total",
+	"memory.free",
+	"memory.used",
+	"utilization.gpu",
+	"temperature.gpu",
+	"power.draw",
+	"driver_version",
+	"pci.bus_id",
+}
+
+// cudaVersionRe matches the CUDA version in the nvidia-smi banner, e.g.
+// "| NVIDIA-SMI 535.104.05   Driver Version: 535.104.05   CUDA Version: 12.2 |"
+var cudaVersionRe = regexp.MustCompile(`CUDA Version:\s*([\d.]+)`)
+
+// nvidiaProbe detects NVIDIA GPUs using nvidia-smi
+type nvidiaProbe struct {
+	run commandFunc
+}
+
+// Vendor returns the vendor name
+func (p nvidiaProbe) Vendor() string { return config.GPUVendorNvidia }
+
+// Devices lists the NVIDIA GPUs
+func (p nvidiaProbe) Devices() ([]GPUDevice, error) {
+	out, err := p.run("nvidia-smi",
+		"--query-gpu="+strings.Join(nvidiaQueryFields, ","),
+		"--format=csv,noheader,nounits")
+	if err != nil {
+		return nil, err
+	}
+	devices, err := parseNvidiaCSV(string(out))
+	if err != nil {
+		return nil, err
+	}
+
+	// The CUDA version is only printed in the banner of plain nvidia-smi
+	if banner, err := p.run("nvidia-smi"); err == nil {
+		cudaVersion := parseCUDAVersion(string(banner))
+		for i := range devices {
+			devices[i].CUDAVersion = cudaVersion
+		}
+	}
+
+	return devices, nil
+}
+
+// parseNvidiaCSV parses the output of nvidia-smi --query-gpu with
+// nvidiaQueryFields and --format=csv,noheader,nounits
+func parseNvidiaCSV(output string) ([]GPUDevice, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimSpace(output)))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nvidia-smi output: %w", err)
+	}
+
+	devices := make([]GPUDevice, 0, len(records))
+	for _, record := range records {
+		if len(record) != len(nvidiaQueryFields) {
+			return nil, fmt.Errorf("unexpected nvidia-smi output: %d fields, want %d", len(record), len(nvidiaQueryFields))
+		}
+		for i := range record {
+			record[i] = nvidiaValue(record[i])
+		}
+
+		index, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU index %q: %w", record[0], err)
+		}
+		devices = append(devices, GPUDevice{
+			Index:         index,
+			UUID:          record[1],
+			Model:         record[2],
+			MemoryTotal:   parseUintOrZero(record[3]),
+			MemoryFree:    parseUintOrZero(record[4]),
+			MemoryUsed:    parseUintOrZero(record[5]),
+			Utilization:   parseFloatOrZero(record[6]),
+			Temperature:   parseFloatOrZero(record[7]),
+			PowerDraw:     parseFloatOrZero(record[8]),
+			DriverVersion: record[9],
+			PCIBusID:      record[10],
+		})
+	}
+	return devices, nil
+}
+
+// parseCUDAVersion extracts the CUDA version from the nvidia-smi banner
+func parseCUDAVersion(banner string) string {
+	if m := cudaVersionRe.FindStringSubmatch(banner); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// nvidiaValue trims a CSV field and blanks the placeholders nvidia-smi
+// prints for unavailable values, such as "[N/A]" or "[Not Supported]"
+func nvidiaValue(field string) string {
+	field = strings.TrimSpace(field)
+	if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") || field == "N/A" {
+		return ""
+	}
+	return field
+}
@@ -0,0 +1,103 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readTestdata returns the contents of a file under testdata
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return string(data)
+}
+
+func TestParseNvidiaCSV(t *testing.T) {
+	got, err := parseNvidiaCSV(readTestdata(t, "nvidia-smi-query.csv"))
+	if err != nil {
+		t.Fatalf("parseNvidiaCSV: %v", err)
+	}
+
+	want := []GPUDevice{
+		{
+			Index:         0,
+			UUID:          "GPU-5c0a1b2c-3d4e-5f60-7182-93a4b5c6d7e8",
+			Model:         "NVIDIA A100-SXM4-80GB",
+			MemoryTotal:   81920,
+			MemoryFree:    81013,
+			MemoryUsed:    0,
+			Utilization:   0,
+			Temperature:   34,
+			PowerDraw:     61.07,
+			DriverVersion: "535.104.05",
+			PCIBusID:      "00000000:07:00.0",
+		},
+		{
+			Index:         1,
+			UUID:          "GPU-6d1b2c3d-4e5f-6071-8293-a4b5c6d7e8f9",
+			Model:         "NVIDIA A100-SXM4-80GB",
+			MemoryTotal:   81920,
+			MemoryFree:    40230,
+			MemoryUsed:    41003,
+			Utilization:   87,
+			Temperature:   71,
+			PowerDraw:     312.45,
+			DriverVersion: "535.104.05",
+			PCIBusID:      "00000000:0F:00.0",
+		},
+		{
+			// Unsupported utilization and unavailable power read as zero
+			Index:         2,
+			UUID:          "GPU-7e2c3d4e-5f60-7182-93a4-b5c6d7e8f9a0",
+			Model:         "NVIDIA GeForce GTX 1080",
+			MemoryTotal:   8192,
+			MemoryFree:    8110,
+			MemoryUsed:    2,
+			Temperature:   41,
+			DriverVersion: "535.104.05",
+			PCIBusID:      "00000000:47:00.0",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNvidiaCSV =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseNvidiaCSVErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{name: "missing fields", output: "0, GPU-1, NVIDIA T4, 15360\n"},
+		{name: "invalid index", output: "[N/A], GPU-1, NVIDIA T4, 15360, 15000, 360, 0, 40, 20, 535.104.05, 00000000:01:00.0\n"},
+		{name: "unbalanced quotes", output: "0, \"GPU-1, NVIDIA T4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseNvidiaCSV(tt.output); err == nil {
+				t.Error("parseNvidiaCSV succeeded")
+			}
+		})
+	}
+}
+
+func TestParseNvidiaCSVEmpty(t *testing.T) {
+	got, err := parseNvidiaCSV("\n")
+	if err != nil || len(got) != 0 {
+		t.Errorf("parseNvidiaCSV(empty) = %v, %v; want no devices", got, err)
+	}
+}
+
+func TestParseCUDAVersion(t *testing.T) {
+	if got := parseCUDAVersion(readTestdata(t, "nvidia-smi-banner.txt")); got != "12.2" {
+		t.Errorf("parseCUDAVersion = %q, want 12.2", got)
+	}
+	if got := parseCUDAVersion("NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver."); got != "" {
+		t.Errorf("parseCUDAVersion without a banner = %q, want empty", got)
+	}
+}
@@ -0,0 +1,264 @@
+package resource
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+// fakeCommands is a commandFunc serving recorded tool output by command
+// line. Unknown commands fail as if the tool were not installed.
+type fakeCommands struct {
+	outputs map[string][]byte
+	calls   []string
+}
+
+func newFakeCommands(t *testing.T, fixtures map[string]string) *fakeCommands {
+	t.Helper()
+	f := &fakeCommands{outputs: make(map[string][]byte)}
+	for command, fixture := range fixtures {
+		f.outputs[command] = []byte(readTestdata(t, fixture))
+	}
+	return f
+}
+
+func (f *fakeCommands) run(name string, args ...string) ([]byte, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, command)
+	if out, ok := f.outputs[command]; ok {
+		return out, nil
+	}
+	return nil, exec.ErrNotFound
+}
+
+func TestNvidiaProbe(t *testing.T) {
+	cmds := newFakeCommands(t, map[string]string{
+		"nvidia-smi --query-gpu=" + strings.Join(nvidiaQueryFields, ",") + " --format=csv,noheader,nounits": "nvidia-smi-query.csv",
+		"nvidia-smi": "nvidia-smi-banner.txt",
+	})
+
+	devices, err := nvidiaProbe{run: cmds.run}.Devices()
+	if err != nil {
+		t.Fatalf("Devices: %v", err)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("got %d devices, want 3", len(devices))
+	}
+	for _, device := range devices {
+		if device.CUDAVersion != "12.2" {
+			t.Errorf("GPU %d CUDA version = %q, want 12.2", device.Index, device.CUDAVersion)
+		}
+	}
+}
+
+func TestNvidiaProbeWithoutBanner(t *testing.T) {
+	cmds := newFakeCommands(t, map[string]string{
+		"nvidia-smi --query-gpu=" + strings.Join(nvidiaQueryFields, ",") + " --format=csv,noheader,nounits": "nvidia-smi-query.csv",
+	})
+
+	devices, err := nvidiaProbe{run: cmds.run}.Devices()
+	if err != nil || len(devices) != 3 {
+		t.Fatalf("Devices = %d devices, %v; want 3", len(devices), err)
+	}
+	if devices[0].CUDAVersion != "" {
+		t.Errorf("CUDA version = %q without a banner, want empty", devices[0].CUDAVersion)
+	}
+}
+
+func TestROCmProbe(t *testing.T) {
+	cmds := newFakeCommands(t, map[string]string{
+		"rocm-smi --showproductname --showmeminfo vram --showuse --showtemp --showpower --showbus --showuniqueid --showdriverversion --json": "rocm-smi.json",
+	})
+
+	got, err := rocmProbe{run: cmds.run}.Devices()
+	if err != nil {
+		t.Fatalf("Devices: %v", err)
+	}
+
+	want := []GPUDevice{
+		{
+			Index:         0,
+			UUID:          "0x9f1a2b3c4d5e6f70",
+			Model:         "Instinct MI210",
+			MemoryTotal:   65520,
+			MemoryFree:    65510,
+			MemoryUsed:    10,
+			Utilization:   0,
+			Temperature:   38,
+			PowerDraw:     42,
+			DriverVersion: "6.7.0",
+			PCIBusID:      "0000:03:00.0",
+		},
+		{
+			// Edge temperature unsupported, junction used instead; no
+			// per-card driver version, the system one applies
+			Index:         1,
+			UUID:          "0x18d5c3a9e02f7b41",
+			Model:         "AMD Instinct MI300X",
+			MemoryTotal:   196288,
+			MemoryFree:    196016,
+			MemoryUsed:    272,
+			Utilization:   23,
+			Temperature:   52,
+			PowerDraw:     145,
+			DriverVersion: "6.3.6",
+			PCIBusID:      "0000:1B:00.0",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Devices =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseROCmJSONErrors(t *testing.T) {
+	if _, err := parseROCmJSON([]byte("WARNING: No AMD GPUs specified")); err == nil {
+		t.Error("parseROCmJSON accepted non-JSON output")
+	}
+	devices, err := parseROCmJSON([]byte(`{"system": {"Driver version": "6.3.6"}, "cardX": {}}`))
+	if err != nil || len(devices) != 0 {
+		t.Errorf("parseROCmJSON without cards = %v, %v; want no devices", devices, err)
+	}
+}
+
+func TestXPUProbe(t *testing.T) {
+	cmds := newFakeCommands(t, map[string]string{
+		"xpu-smi discovery -j":      "xpu-smi-discovery.json",
+		"xpu-smi discovery -d 0 -j": "xpu-smi-discovery-0.json",
+		"xpu-smi stats -d 0 -j":     "xpu-smi-stats-0.json",
+	})
+
+	got, err := xpuProbe{run: cmds.run}.Devices()
+	if err != nil {
+		t.Fatalf("Devices: %v", err)
+	}
+
+	want := []GPUDevice{
+		{
+			Index:         0,
+			UUID:          "01000000-0000-0000-0000-000000290000",
+			Model:         "Intel(R) Data Center GPU Max 1550",
+			MemoryTotal:   65536,
+			MemoryFree:    63488,
+			MemoryUsed:    2048,
+			Utilization:   12,
+			Temperature:   45,
+			PowerDraw:     185.2,
+			DriverVersion: "I915_23.10.72_PSB_230926.32",
+			PCIBusID:      "0000:29:00.0",
+		},
+		{
+			// Details and stats are best-effort and failed for this device
+			Index:    1,
+			UUID:     "01000000-0000-0000-0000-0000003a0000",
+			Model:    "Intel(R) Data Center GPU Flex 170",
+			PCIBusID: "0000:3a:00.0",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Devices =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseXPUDiscoverySkipsOtherDevices(t *testing.T) {
+	devices, err := parseXPUDiscovery([]byte(`{"device_list": [
+		{"device_id": 0, "device_name": "GPU", "device_type": "GPU"},
+		{"device_id": 1, "device_name": "Accelerator", "device_type": "NPU"},
+		{"device_id": 2, "device_name": "Untyped"}
+	]}`))
+	if err != nil {
+		t.Fatalf("parseXPUDiscovery: %v", err)
+	}
+	if len(devices) != 2 || devices[0].Index != 0 || devices[1].Index != 2 {
+		t.Errorf("parseXPUDiscovery = %+v, want devices 0 and 2", devices)
+	}
+	if _, err := parseXPUDiscovery([]byte("xpu-smi: command failed")); err == nil {
+		t.Error("parseXPUDiscovery accepted non-JSON output")
+	}
+}
+
+// stubProbe is a GPUProbe with fixed results
+type stubProbe struct {
+	vendor  string
+	devices []GPUDevice
+	err     error
+	called  *[]string
+}
+
+func (p stubProbe) Vendor() string { return p.vendor }
+
+func (p stubProbe) Devices() ([]GPUDevice, error) {
+	*p.called = append(*p.called, p.vendor)
+	return p.devices, p.err
+}
+
+func TestDetectGPUsOrder(t *testing.T) {
+	var called []string
+	probes := []GPUProbe{
+		stubProbe{vendor: config.GPUVendorNvidia, err: errors.New("nvidia-smi not found"), called: &called},
+		stubProbe{vendor: config.GPUVendorAMD, devices: []GPUDevice{}, called: &called},
+		stubProbe{vendor: config.GPUVendorIntel, devices: []GPUDevice{{Index: 0, Model: "Max 1550"}}, called: &called},
+		stubProbe{vendor: "other", devices: []GPUDevice{{Index: 0, Model: "unused"}}, called: &called},
+	}
+
+	vendor, devices := detectGPUs(probes)
+	if vendor != config.GPUVendorIntel || len(devices) != 1 || devices[0].Model != "Max 1550" {
+		t.Errorf("detectGPUs = %q %+v, want the intel device", vendor, devices)
+	}
+	if want := []string{"nvidia", "amd", "intel"}; !reflect.DeepEqual(called, want) {
+		t.Errorf("probes called = %v, want %v", called, want)
+	}
+}
+
+func TestDetectGPUsNone(t *testing.T) {
+	var called []string
+	vendor, devices := detectGPUs([]GPUProbe{
+		stubProbe{vendor: config.GPUVendorNvidia, err: errors.New("not found"), called: &called},
+	})
+	if vendor != "" || devices == nil || len(devices) != 0 {
+		t.Errorf("detectGPUs = %q %v, want no vendor and an empty list", vendor, devices)
+	}
+}
+
+func TestNewGPUProbes(t *testing.T) {
+	tests := []struct {
+		vendor  string
+		want    []string
+		wantErr bool
+	}{
+		{vendor: config.GPUVendorAuto, want: []string{"nvidia", "amd", "intel"}},
+		{vendor: "", want: []string{"nvidia", "amd", "intel"}},
+		{vendor: config.GPUVendorAMD, want: []string{"amd"}},
+		{vendor: config.GPUVendorNone},
+		{vendor: "matrox", wantErr: true},
+	}
+	for _, tt := range tests {
+		probes, err := NewGPUProbes(tt.vendor)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewGPUProbes(%q) error = %v, wantErr %v", tt.vendor, err, tt.wantErr)
+			continue
+		}
+		var vendors []string
+		for _, probe := range probes {
+			vendors = append(vendors, probe.Vendor())
+		}
+		if !reflect.DeepEqual(vendors, tt.want) {
+			t.Errorf("NewGPUProbes(%q) vendors = %v, want %v", tt.vendor, vendors, tt.want)
+		}
+	}
+}
+
+func TestSummarizeGPUs(t *testing.T) {
+	r := &Resources{GPUs: []GPUDevice{
+		{Model: "A100", MemoryTotal: 81920, MemoryFree: 80000},
+		{Model: "T4", MemoryTotal: 15360, MemoryFree: 15000},
+	}}
+	summarizeGPUs(r)
+	if r.GPUCount != 2 || r.GPUModel != "A100" || r.GPUMemoryTotal != 97280 || r.GPUMemoryFree != 95000 {
+		t.Errorf("summarizeGPUs = count %d model %q total %d free %d",
+			r.GPUCount, r.GPUModel, r.GPUMemoryTotal, r.GPUMemoryFree)
+	}
+}
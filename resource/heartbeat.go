@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/version"
+)
+
+// HeartbeatSchemaVersion identifies the layout of the heartbeat payload.
+// It is bumped on incompatible changes so the manager can support several
+// agent versions at once.
+const HeartbeatSchemaVersion = 1
+
+// RuntimeState describes the local Ray runtime as reported in heartbeats
+type RuntimeState struct {
+	Running bool   `json:"running"`
+	Role    string `json:"role,omitempty"`    // Applied role, empty if unknown
+	HeadIP  string `json:"head_ip,omitempty"` // Head address for worker nodes
+}
+
+// Heartbeat is the payload of POST /api/heartbeat
+type Heartbeat struct {
+	SchemaVersion   int           `json:"schema_version"`
+	NodeID          string        `json:"node_id"`
+	Sequence        uint64        `json:"seq"` // Increases by one per heartbeat since agent start
+	Timestamp       int64         `json:"timestamp"`
+	AgentVersion    string        `json:"agent_version"`
+	UptimeSeconds   int64         `json:"uptime_seconds"`
+	IntervalSeconds int64         `json:"interval_seconds"` // Expected time until the next heartbeat
+	Resources       *Resources    `json:"resources,omitempty"`
+	Ray             *RuntimeState `json:"ray,omitempty"`
+}
+
+// SetRuntimeStateFunc registers the callback reporting the Ray runtime
+// state in heartbeats. The ray package depends on this one, so the state is
+// pulled through a callback rather than imported.
+func (m *Manager) SetRuntimeStateFunc(fn func() RuntimeState) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+	m.runtimeStateFn = fn
+}
+
+// buildHeartbeat assembles the next heartbeat with freshly collected
+// resource usage
+func (m *Manager) buildHeartbeat() *Heartbeat {
+	hb := &Heartbeat{
+		SchemaVersion:   HeartbeatSchemaVersion,
+		NodeID:          m.NodeID(),
+		Sequence:        atomic.AddUint64(&m.heartbeatSeq, 1),
+		Timestamp:       time.Now().Unix(),
+		AgentVersion:    version.Version,
+		UptimeSeconds:   int64(time.Since(m.startedAt).Seconds()),
+		IntervalSeconds: int64(m.config.Load().HeartbeatInterval.Seconds()),
+	}
+
+	// Free memory, disk and GPU memory change constantly, so refresh them
+	if resources, err := m.GetResources(true); err != nil {
+		m.log.Warn("Could not refresh resources for heartbeat", "error", err)
+	} else {
+		hb.Resources = resources
+	}
+
+	m.stateMutex.RLock()
+	runtimeStateFn := m.runtimeStateFn
+	m.stateMutex.RUnlock()
+	if runtimeStateFn != nil {
+		state := runtimeStateFn()
+		hb.Ray = &state
+	}
+
+	return hb
+}
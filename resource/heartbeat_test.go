@@ -0,0 +1,197 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager/managertest"
+)
+
+// newTestManager returns a Manager of the fake manager srv that skips GPU
+// and geolocation probes and retries immediately
+func newTestManager(t *testing.T, srv *managertest.Server, dataDir string) *Manager {
+	t.Helper()
+	m, err := NewManager(config.NewStore(&config.Config{
+		ManagerIP:         srv.URL,
+		DataDir:           dataDir,
+		GPUVendor:         config.GPUVendorNone,
+		GeoProvider:       config.GeoProviderNone,
+		HeartbeatInterval: time.Minute,
+	}))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	m.ManagerClient().SetBackoff(manager.Backoff{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond})
+	return m
+}
+
+// decodeHeartbeats decodes the heartbeats srv accepted
+func decodeHeartbeats(t *testing.T, srv *managertest.Server) []Heartbeat {
+	t.Helper()
+	var heartbeats []Heartbeat
+	for _, raw := range srv.Heartbeats() {
+		var hb Heartbeat
+		if err := json.Unmarshal(raw, &hb); err != nil {
+			t.Fatalf("decoding heartbeat: %v", err)
+		}
+		heartbeats = append(heartbeats, hb)
+	}
+	return heartbeats
+}
+
+func TestSendHeartbeatRegistersFirst(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	srv.RequireSignatures()
+	m := newTestManager(t, srv, t.TempDir())
+	m.SetRuntimeStateFunc(func() RuntimeState { return RuntimeState{Running: true, Role: "head"} })
+
+	for i := 0; i < 2; i++ {
+		if err := m.SendHeartbeat(context.Background()); err != nil {
+			t.Fatalf("SendHeartbeat: %v", err)
+		}
+	}
+
+	regs := srv.Registrations()
+	if len(regs) != 1 || regs[0].PublicKey != m.PublicKey() || regs[0].NodeID != "" {
+		t.Fatalf("registrations = %+v, want one with the node key and no previous ID", regs)
+	}
+	if !m.IsRegistered() || m.NodeID() != "node-1" {
+		t.Errorf("registered %v as %q, want node-1", m.IsRegistered(), m.NodeID())
+	}
+
+	heartbeats := decodeHeartbeats(t, srv)
+	if len(heartbeats) != 2 {
+		t.Fatalf("manager accepted %d heartbeats, want 2", len(heartbeats))
+	}
+	for i, hb := range heartbeats {
+		if hb.NodeID != "node-1" || hb.Sequence != uint64(i+1) || hb.SchemaVersion != HeartbeatSchemaVersion ||
+			hb.IntervalSeconds != 60 || hb.Resources == nil || hb.Ray == nil || hb.Ray.Role != "head" {
+			t.Errorf("heartbeat %d = %+v", i, hb)
+		}
+	}
+}
+
+func TestSendHeartbeatRetriesTransientFailures(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	m := newTestManager(t, srv, t.TempDir())
+
+	srv.Fail("/api/register", 503)
+	srv.Fail("/api/heartbeat", 502, 503)
+	if err := m.SendHeartbeat(context.Background()); err != nil {
+		t.Fatalf("SendHeartbeat: %v", err)
+	}
+	if got := srv.Requests("/api/register"); got != 2 {
+		t.Errorf("registration requests = %d, want 2", got)
+	}
+	if got := srv.Requests("/api/heartbeat"); got != 3 {
+		t.Errorf("heartbeat requests = %d, want 3", got)
+	}
+	if got := len(srv.Registrations()); got != 1 {
+		t.Errorf("transient heartbeat failures caused %d registrations, want 1", got)
+	}
+
+	// Once the attempts are used up the heartbeat fails without
+	// registering again
+	srv.Fail("/api/heartbeat", 503, 503, 503)
+	if err := m.SendHeartbeat(context.Background()); !manager.Retryable(err) {
+		t.Errorf("SendHeartbeat = %v, want the retryable 503", err)
+	}
+	if got := len(srv.Registrations()); got != 1 || !m.IsRegistered() {
+		t.Errorf("registrations = %d, registered %v; want 1 and still registered", got, m.IsRegistered())
+	}
+}
+
+func TestSendHeartbeatReregistersWhenRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		reject func(srv *managertest.Server)
+		wantID string // ID after registering again
+	}{
+		{
+			name:   "404 for a forgotten node",
+			reject: func(srv *managertest.Server) { srv.Forget("node-1") },
+			wantID: "node-2",
+		},
+		{
+			name:   "401",
+			reject: func(srv *managertest.Server) { srv.Fail("/api/heartbeat", 401) },
+			wantID: "node-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := managertest.NewServer()
+			defer srv.Close()
+			m := newTestManager(t, srv, t.TempDir())
+			if err := m.SendHeartbeat(context.Background()); err != nil {
+				t.Fatalf("SendHeartbeat: %v", err)
+			}
+
+			tt.reject(srv)
+			if err := m.SendHeartbeat(context.Background()); err != nil {
+				t.Fatalf("SendHeartbeat after rejection: %v", err)
+			}
+
+			regs := srv.Registrations()
+			if len(regs) != 2 || regs[1].NodeID != "node-1" {
+				t.Fatalf("registrations = %+v, want a second one asking to keep node-1", regs)
+			}
+			if m.NodeID() != tt.wantID || !m.IsRegistered() {
+				t.Errorf("registered %v as %q, want %s", m.IsRegistered(), m.NodeID(), tt.wantID)
+			}
+			heartbeats := decodeHeartbeats(t, srv)
+			if len(heartbeats) != 2 || heartbeats[1].NodeID != tt.wantID {
+				t.Errorf("heartbeats = %+v, want the retried one from %s", heartbeats, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestNodeIDSurvivesRestart(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	srv.RequireSignatures()
+	dataDir := t.TempDir()
+
+	first := newTestManager(t, srv, dataDir)
+	if err := first.SendHeartbeat(context.Background()); err != nil {
+		t.Fatalf("SendHeartbeat: %v", err)
+	}
+
+	// The restarted node reuses its key and ID, so its signed heartbeat
+	// is accepted without registering again
+	second := newTestManager(t, srv, dataDir)
+	if second.NodeID() != "node-1" || second.PublicKey() != first.PublicKey() {
+		t.Fatalf("restarted as %q with a new key %v, want node-1 and the same key",
+			second.NodeID(), second.PublicKey() != first.PublicKey())
+	}
+	if err := second.SendHeartbeat(context.Background()); err != nil {
+		t.Fatalf("SendHeartbeat after restart: %v", err)
+	}
+	if got := len(srv.Registrations()); got != 1 {
+		t.Errorf("registrations = %d, want 1", got)
+	}
+}
+
+func TestDeregisterNode(t *testing.T) {
+	srv := managertest.NewServer()
+	defer srv.Close()
+	m := newTestManager(t, srv, t.TempDir())
+	if err := m.SendHeartbeat(context.Background()); err != nil {
+		t.Fatalf("SendHeartbeat: %v", err)
+	}
+
+	if err := m.DeregisterNode(context.Background()); err != nil {
+		t.Fatalf("DeregisterNode: %v", err)
+	}
+	deregs := srv.Deregistrations()
+	if len(deregs) != 1 || deregs[0].NodeID != "node-1" || m.IsRegistered() {
+		t.Errorf("deregistrations = %+v, registered %v", deregs, m.IsRegistered())
+	}
+}
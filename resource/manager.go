@@ -2,58 +2,99 @@ package resource
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/showwin/speedtest-go/speedtest"
+	"github.com/unicornultrafoundation/subnet-rayai-node/bandwidth"
 	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/health"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/metrics"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/tlsutil"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
+	"github.com/unicornultrafoundation/subnet-rayai-node/signing"
 )
 
 // Resources represents system resources
 type Resources struct {
-	CPUCount       int    `json:"cpu"`
-	CPUName        string `json:"cpu_name,omitempty"`
-	MemoryTotal    uint64 `json:"memory_total"`
-	MemoryFree     uint64 `json:"memory_free"`
-	DiskTotal      uint64 `json:"disk_total"`
-	DiskFree       uint64 `json:"disk_free"`
-	GPUCount       int    `json:"gpu"`
-	GPUModel       string `json:"gpu_model,omitempty"`
-	GPUMemoryTotal uint64 `json:"gpu_memory_total,omitempty"` // MiB
-	GPUMemoryFree  uint64 `json:"gpu_memory_free,omitempty"`  // MiB
+	CPUCount       int          `json:"cpu"` // Usable CPUs, capped by the cgroup quota
+	CPUName        string       `json:"cpu_name,omitempty"`
+	MemoryTotal    uint64       `json:"memory_total"` // Usable memory, capped by the cgroup limit
+	MemoryFree     uint64       `json:"memory_free"`  // Available memory, including reclaimable cache
+	DiskTotal      uint64       `json:"disk_total"`   // Summed over distinct filesystems in Disks
+	DiskFree       uint64       `json:"disk_free"`
+	Disks          []DiskVolume `json:"disks"`
+	GPUCount       int          `json:"gpu"`
+	GPUModel       string       `json:"gpu_model,omitempty"`        // Model of the first GPU
+	GPUMemoryTotal uint64       `json:"gpu_memory_total,omitempty"` // MiB, summed over all GPUs
+	GPUMemoryFree  uint64       `json:"gpu_memory_free,omitempty"`  // MiB, summed over all GPUs
+	GPUVendor      string       `json:"gpu_vendor,omitempty"`       // e.g. nvidia, amd, intel
+	GPUs           []GPUDevice  `json:"gpus"`
+
+	// Host figures, which differ from the above inside a container
+	HostCPUCount        int     `json:"host_cpu"`
+	CPUQuota            float64 `json:"cpu_quota,omitempty"` // CPUs allowed by the cgroup, 0 if unlimited
+	HostMemoryTotal     uint64  `json:"host_memory_total"`
+	HostMemoryAvailable uint64  `json:"host_memory_available"`
+	MemoryLimit         uint64  `json:"memory_limit,omitempty"`       // cgroup limit, 0 if unlimited
+	MemoryWorkingSet    uint64  `json:"memory_working_set,omitempty"` // cgroup usage minus inactive cache
 }
 
 // Manager handles resource management and node registration
 type Manager struct {
-	config     *config.Config
+	config     *config.Store // Live configuration, replaced on reload
+	log        *slog.Logger
 	mutex      sync.RWMutex
 	resources  *Resources
 	lastUpdate time.Time
 	updateFreq time.Duration
-	client     *http.Client
-	registered bool // Add explicit registration status flag
-
-	// Geo location cache
-	geoMutex  sync.RWMutex
-	geoCache  *GeoLocation
-	geoExpiry time.Time
-
-	// Bandwidth cache
+	manager    *manager.Client // Manager API, requests signed with the node key
+	extClient  *http.Client    // Third-party lookups, never signed
+	nodeKey    ed25519.PrivateKey
+	gpuProbes  []GPUProbe // Tried in order by GetResources
+
+	// Registration state, persisted under the data directory
+	stateMutex sync.RWMutex
+	registered bool   // Add explicit registration status flag
+	nodeID     string // Assigned by the manager at registration
+
+	// Background loops started by StartHeartbeat and StartBackgroundUpdater
+	wg sync.WaitGroup
+
+	// Heartbeat state
+	startedAt      time.Time
+	heartbeatSeq   uint64              // Accessed atomically
+	runtimeStateFn func() RuntimeState // Guarded by stateMutex
+	busyFn         func() bool         // Guarded by stateMutex
+
+	// Last known geo location
+	geoProvider GeoProvider // nil when disabled
+	geoMutex    sync.RWMutex
+	geoCache    *GeoLocation
+	geoFlight   flight
+
+	// Last internet speed test
 	bwMutex   sync.RWMutex
 	bwCache   *Bandwidth
-	bwExpiry  time.Time
+	bwFlight  flight
 	speedTest *speedtest.Speedtest
+
+	// Last peer bandwidth measurements
+	peerClient  *bandwidth.Client
+	peerMutex   sync.RWMutex
+	peerResults []bandwidth.Result
+	peerFlight  flight
 }
 
 // GeoLocation stores node geographical information
@@ -75,180 +116,281 @@ type Bandwidth struct {
 	MeasuredAt   int64   `json:"measured_at"`
 }
 
+// NodeInfo describes this node's identity towards the manager
+type NodeInfo struct {
+	NodeID     string `json:"node_id,omitempty"`
+	PublicKey  string `json:"public_key"` // Base64 Ed25519 key signing manager requests
+	Registered bool   `json:"registered"`
+	Hostname   string `json:"hostname"`
+}
+
+// keyFileName is the file under the data directory holding the node key
+const keyFileName = "node.key"
+
+// heartbeatStallMargin is how late a heartbeat may be before the loop is
+// reported as stuck
+const heartbeatStallMargin = 5 * time.Minute
+
+// speedTestTimeout bounds a whole internet speed test
+const speedTestTimeout = 5 * time.Minute
+
+// errNotMeasured is returned for a probe that has no result yet
+var errNotMeasured = errors.New("not measured yet")
+
 // NewManager creates a new resource manager
-func NewManager(cfg *config.Config) *Manager {
+func NewManager(store *config.Store) (*Manager, error) {
+	cfg := store.Load()
+
+	// Every manager request is signed with the node's persistent key
+	nodeKey, err := signing.LoadOrCreateKey(filepath.Join(cfg.DataDir, keyFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := newManagerTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	peerClient, err := newPeerClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	gpuProbes, err := NewGPUProbes(cfg.GPUVendor)
+	if err != nil {
+		return nil, err
+	}
+
+	extClient := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	geoProvider, err := NewGeoProvider(cfg, extClient)
+	if err != nil {
+		return nil, err
+	}
+
 	m := &Manager{
-		config:     cfg,
+		config:     store,
+		log:        logging.For("resource"),
 		updateFreq: time.Minute * 5, // Update resource data every 5 minutes
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		speedTest: speedtest.New(),
+		manager: manager.NewClient(store, &http.Client{
+			Transport: signing.NewTransport(transport, nodeKey),
+		}),
+		extClient:   extClient,
+		geoProvider: geoProvider,
+		peerClient:  peerClient,
+		nodeKey:     nodeKey,
+		gpuProbes:   gpuProbes,
+		startedAt:   time.Now(),
+		speedTest:   speedtest.New(),
+	}
+
+	// Reuse the node ID from a previous run; the manager is asked to
+	// register us again only if it rejects the ID
+	state, err := loadState(cfg.DataDir)
+	if err != nil {
+		return nil, err
 	}
+	if state.NodeID != "" {
+		m.nodeID = state.NodeID
+		m.registered = true
+		logging.SetNodeID(state.NodeID)
+		m.log.Info("Loaded node ID", "node_id", state.NodeID)
+	}
+
+	// A manager that no longer knows the node is asked to register it again
+	m.manager.SetRejectedFunc(func() { m.setRegistered(false) })
 
-	// Initial resource update
-	_, _ = m.GetResources(true)
+	return m, nil
+}
 
-	return m
+// newManagerTransport returns the transport for manager requests, with the
+// configured CA bundle and client certificate for HTTPS managers
+func newManagerTransport(cfg *config.Config) (*http.Transport, error) {
+	clientTLS, err := tlsutil.NewClientTLS(cfg.ManagerCAFile, cfg.ManagerClientCertFile, cfg.ManagerClientKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manager TLS config: %w", err)
+	}
+	return clientTLS.Transport(), nil
 }
 
-// StartHeartbeat begins sending periodic heartbeats to the manager
-func (m *Manager) StartHeartbeat(interval time.Duration) {
+// StartHeartbeat begins sending periodic heartbeats to the manager every
+// HeartbeatInterval until ctx is done, beating loop after each one. A
+// reloaded interval applies from the last heartbeat.
+func (m *Manager) StartHeartbeat(ctx context.Context, loop *health.Loop) {
+	// A heartbeat may register first and check the Ray runtime, which
+	// together take well under heartbeatStallMargin unless something hangs
+	loop.Beat(m.heartbeatInterval() + heartbeatStallMargin)
+
+	m.wg.Add(1)
 	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
+		defer m.wg.Done()
 
+		last := time.Now()
 		for {
-			<-ticker.C
-			if err := m.SendHeartbeat(); err != nil {
-				log.Printf("Failed to send heartbeat: %v", err)
+			changed := m.config.Changed()
+			next := last.Add(m.heartbeatInterval())
+			loop.Beat(time.Until(next) + heartbeatStallMargin)
+
+			due, ok := sleepUntil(ctx, next, changed)
+			if !ok {
+				return
+			}
+			if !due {
+				continue // Config reloaded, reschedule
+			}
+
+			if err := m.SendHeartbeat(ctx); err != nil {
+				m.log.Warn("Failed to send heartbeat", "error", err)
 			}
+			last = time.Now()
 		}
 	}()
 }
 
+// heartbeatInterval returns the configured time between heartbeats
+func (m *Manager) heartbeatInterval() time.Duration {
+	if interval := m.config.Load().HeartbeatInterval; interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// Wait blocks until all background loops have exited
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
 // SendHeartbeat sends a heartbeat to the manager with all node information
-func (m *Manager) SendHeartbeat() error {
-	if m.config.ManagerIP == "" {
-		return fmt.Errorf("manager IP not configured")
+func (m *Manager) SendHeartbeat(ctx context.Context) error {
+	if !m.manager.Configured() {
+		return manager.ErrNotConfigured
 	}
 
-	if !m.registered {
+	if !m.IsRegistered() {
 		// Not registered yet, try registering first
-		if err := m.RegisterNode(); err != nil {
+		if err := m.RegisterNode(ctx); err != nil {
 			return fmt.Errorf("cannot send heartbeat, node not registered: %w", err)
 		}
 	}
 
-	// Send heartbeat to manager
-	url := fmt.Sprintf("http://%s/api/heartbeat", m.config.ManagerIP)
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create heartbeat request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := m.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send heartbeat: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("heartbeat failed with status: %s", resp.Status)
+	err := m.sendHeartbeat(ctx)
+	if errors.Is(err, manager.ErrNodeRejected) {
+		// The manager forgot about us, register again and retry once
+		m.log.Warn("Manager rejected node ID, registering again")
+		if err := m.RegisterNode(ctx); err != nil {
+			return fmt.Errorf("cannot send heartbeat, re-registration failed: %w", err)
+		}
+		err = m.sendHeartbeat(ctx)
 	}
+	return err
+}
 
-	return nil
+// sendHeartbeat posts a single heartbeat identifying this node
+func (m *Manager) sendHeartbeat(ctx context.Context) (err error) {
+	defer func() { metrics.RecordHeartbeat(err) }()
+	return m.manager.Heartbeat(ctx, HeartbeatSchemaVersion, m.buildHeartbeat())
 }
 
-// GetGeoLocation returns the node's geographical location
+// GetGeoLocation returns the node's last known location, looking it up
+// first if there is none yet, or nil if geolocation is disabled
 func (m *Manager) GetGeoLocation() (*GeoLocation, error) {
-	m.geoMutex.RLock()
-	if m.geoCache != nil && time.Now().Before(m.geoExpiry) {
-		defer m.geoMutex.RUnlock()
-		return m.geoCache, nil
+	if m.geoProvider == nil {
+		return nil, nil
 	}
-	m.geoMutex.RUnlock()
-
-	// Need to refresh the cache
-	m.geoMutex.Lock()
-	defer m.geoMutex.Unlock()
 
-	// Check again after acquiring write lock
-	if m.geoCache != nil && time.Now().Before(m.geoExpiry) {
-		return m.geoCache, nil
+	m.geoMutex.RLock()
+	geo := m.geoCache
+	m.geoMutex.RUnlock()
+	if geo != nil {
+		return geo, nil
 	}
 
-	// Make request to ip-api.com
-	resp, err := m.client.Get("http://ip-api.com/json/")
-	if err != nil {
-		return nil, fmt.Errorf("error getting geo location: %v", err)
-	}
-	defer resp.Body.Close()
+	return m.refreshGeoLocation()
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading geo response: %v", err)
-	}
+// refreshGeoLocation looks up the node's location, or waits for the lookup
+// already in progress. The last known location is kept if it fails.
+func (m *Manager) refreshGeoLocation() (*GeoLocation, error) {
+	err := m.geoFlight.Do(func() error {
+		geo, err := m.geoProvider.Lookup()
+		if err != nil {
+			return err
+		}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("error parsing geo response: %v", err)
+		m.geoMutex.Lock()
+		m.geoCache = geo
+		m.geoMutex.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	geo := &GeoLocation{
-		IP:      fmt.Sprintf("%v", result["query"]),
-		City:    fmt.Sprintf("%v", result["city"]),
-		Region:  fmt.Sprintf("%v", result["regionName"]),
-		Country: fmt.Sprintf("%v", result["country"]),
-	}
+	m.geoMutex.RLock()
+	defer m.geoMutex.RUnlock()
+	return m.geoCache, nil
+}
 
-	if lat, ok := result["lat"].(float64); ok {
-		geo.Latitude = lat
-	}
-	if lon, ok := result["lon"].(float64); ok {
-		geo.Longitude = lon
+// GetBandwidth returns the node's internet bandwidth. Without forceUpdate
+// the last measurement is returned immediately, however old; a forced
+// update runs a speed test bounded by ctx, or waits for the one already
+// running.
+func (m *Manager) GetBandwidth(ctx context.Context, forceUpdate bool) (*Bandwidth, error) {
+	if forceUpdate {
+		err := m.bwFlight.Do(func() error { return m.runSpeedTest(ctx) })
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Cache for 4 hours
-	m.geoCache = geo
-	m.geoExpiry = time.Now().Add(4 * time.Hour)
-
-	return geo, nil
-}
-
-// GetBandwidth returns the node's network bandwidth
-func (m *Manager) GetBandwidth(forceUpdate bool) (*Bandwidth, error) {
 	m.bwMutex.RLock()
-	if !forceUpdate && m.bwCache != nil && time.Now().Before(m.bwExpiry) {
-		defer m.bwMutex.RUnlock()
-		return m.bwCache, nil
+	defer m.bwMutex.RUnlock()
+	if m.bwCache == nil {
+		return nil, errNotMeasured
 	}
-	m.bwMutex.RUnlock()
+	return m.bwCache, nil
+}
 
-	// Need to refresh the cache
-	m.bwMutex.Lock()
-	defer m.bwMutex.Unlock()
+// runSpeedTest measures internet bandwidth against the closest speedtest.net
+// server and stores the result, giving up after speedTestTimeout or when
+// ctx is done. The bandwidth lock is not held meanwhile, so readers keep
+// getting the previous value.
+func (m *Manager) runSpeedTest(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, speedTestTimeout)
+	defer cancel()
 
-	// Check again after acquiring write lock
-	if !forceUpdate && m.bwCache != nil && time.Now().Before(m.bwExpiry) {
-		return m.bwCache, nil
-	}
-
-	serverList, err := m.speedTest.FetchServers()
+	serverList, err := m.speedTest.FetchServerListContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching speedtest servers: %v", err)
+		return fmt.Errorf("error fetching speedtest servers: %w", err)
 	}
-
 	if len(serverList) < 1 {
-		return nil, fmt.Errorf("no speedtest servers found")
+		return fmt.Errorf("no speedtest servers found")
 	}
 
-	targets := serverList[:1] // Use the closest server
-	for _, server := range targets {
-		server.PingTest(func(latency time.Duration) {
-			// Optionally handle latency here, e.g., log or assign to server.Latency
-			server.Latency = latency
-		})
-		server.DownloadTest()
-		server.UploadTest()
-
-		bw := &Bandwidth{
-			DownloadMbps: server.DLSpeed.Mbps(),
-			UploadMbps:   server.ULSpeed.Mbps(),
-			PingMs:       server.Latency.Milliseconds(),
-			Jitter:       server.Jitter.Milliseconds(),
-			MeasuredAt:   time.Now().Unix(),
-		}
-
-		// Cache for 24 hours
-		m.bwCache = bw
-		m.bwExpiry = time.Now().Add(24 * time.Hour)
+	server := serverList[0] // Use the closest server
+	if err := server.PingTestContext(ctx, nil); err != nil {
+		return fmt.Errorf("ping test failed: %w", err)
+	}
+	if err := server.DownloadTestContext(ctx); err != nil {
+		return fmt.Errorf("download test failed: %w", err)
+	}
+	if err := server.UploadTestContext(ctx); err != nil {
+		return fmt.Errorf("upload test failed: %w", err)
+	}
 
-		return bw, nil
+	bw := &Bandwidth{
+		DownloadMbps: server.DLSpeed.Mbps(),
+		UploadMbps:   server.ULSpeed.Mbps(),
+		PingMs:       server.Latency.Milliseconds(),
+		Jitter:       server.Jitter.Milliseconds(),
+		MeasuredAt:   time.Now().Unix(),
 	}
 
-	return nil, fmt.Errorf("bandwidth test failed to complete")
+	m.bwMutex.Lock()
+	m.bwCache = bw
+	m.bwMutex.Unlock()
+	return nil
 }
 
 // GetResources returns node resource information (CPU, RAM, Disk, GPU)
@@ -268,55 +410,23 @@ func (m *Manager) GetResources(forceUpdate bool) (*Resources, error) {
 		return m.resources, nil
 	}
 
-	// CPU
-	cpuCount := runtime.NumCPU()
-
-	// Memory
-	var memStats syscall.Sysinfo_t
-	memTotal := uint64(0)
-	memFree := uint64(0)
-	if err := syscall.Sysinfo(&memStats); err == nil {
-		memTotal = memStats.Totalram * uint64(memStats.Unit)
-		memFree = memStats.Freeram * uint64(memStats.Unit)
-	}
-
-	// Disk
-	var stat syscall.Statfs_t
-	diskTotal := uint64(0)
-	diskFree := uint64(0)
-	if err := syscall.Statfs("/data", &stat); err == nil {
-		diskTotal = stat.Blocks * uint64(stat.Bsize)
-		diskFree = stat.Bfree * uint64(stat.Bsize)
-	}
-
-	// GPU (try nvidia-smi, fallback to 0)
-	gpuCount := 0
-	gpuModel := ""
-	var gpuMemTotal uint64 = 0
-	var gpuMemFree uint64 = 0
-
-	// Use nvidia-smi to query GPU name, total memory, and free memory (in MiB)
-	cmd := exec.Command("nvidia-smi", "--query-gpu=name,memory.total,memory.free", "--format=csv,noheader,nounits")
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	if err := cmd.Run(); err == nil {
-		lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
-		gpuCount = len(lines)
-		if gpuCount > 0 {
-			// Only extract info from the first GPU (if multiple GPUs exist)
-			parts := bytes.Split(lines[0], []byte(","))
-			if len(parts) >= 3 {
-				gpuModel = string(bytes.TrimSpace(parts[0]))
-				// memory.total and memory.free are in MiB (no "MiB" suffix due to nounits)
-				if mt, err := parseUint64(bytes.TrimSpace(parts[1])); err == nil {
-					gpuMemTotal = mt
-				}
-				if mf, err := parseUint64(bytes.TrimSpace(parts[2])); err == nil {
-					gpuMemFree = mf
-				}
-			}
-		}
+	// CPU, honoring the container's cpuset and quota. runtime.NumCPU is
+	// the process's affinity, not the host count.
+	cpu := readCPUStats(hostRoot, runtime.NumCPU())
+
+	// Memory, from MemAvailable and the container's limit
+	mem, err := readMemoryStats(hostRoot)
+	if err != nil {
+		m.log.Warn("Failed to read memory stats", "error", err)
 	}
+	memTotal, memFree := mem.Effective()
+
+	// Disk, per configured volume
+	volumes := readDiskVolumes(hostRoot, m.config.Load().DiskPaths)
+	diskTotal, diskFree := sumDiskVolumes(volumes)
+
+	// GPUs, from the first vendor tool that finds any
+	gpuVendor, gpus := detectGPUs(m.gpuProbes)
 
 	// Get CPU name (Linux only)
 	cpuName := ""
@@ -334,34 +444,36 @@ func (m *Manager) GetResources(forceUpdate bool) (*Resources, error) {
 	}
 
 	m.resources = &Resources{
-		CPUCount:       cpuCount,
-		CPUName:        cpuName,
-		MemoryTotal:    memTotal,
-		MemoryFree:     memFree,
-		DiskTotal:      diskTotal,
-		DiskFree:       diskFree,
-		GPUCount:       gpuCount,
-		GPUModel:       gpuModel,
-		GPUMemoryTotal: gpuMemTotal,
-		GPUMemoryFree:  gpuMemFree,
-	}
+		CPUCount:            cpu.Effective(),
+		CPUName:             cpuName,
+		MemoryTotal:         memTotal,
+		MemoryFree:          memFree,
+		DiskTotal:           diskTotal,
+		DiskFree:            diskFree,
+		Disks:               volumes,
+		GPUVendor:           gpuVendor,
+		GPUs:                gpus,
+		HostCPUCount:        cpu.HostCount,
+		CPUQuota:            cpu.Quota,
+		HostMemoryTotal:     mem.HostTotal,
+		HostMemoryAvailable: mem.HostAvailable,
+		MemoryLimit:         mem.Limit,
+		MemoryWorkingSet:    mem.WorkingSet,
+	}
+	summarizeGPUs(m.resources)
 	m.lastUpdate = time.Now()
 
 	return m.resources, nil
 }
 
-// parseUint64 parses a byte slice to uint64
-func parseUint64(b []byte) (uint64, error) {
-	return strconv.ParseUint(string(b), 10, 64)
-}
-
 // RegisterNode registers the node with the manager
-func (m *Manager) RegisterNode() error {
-	if m.config.ManagerIP == "" {
-		return fmt.Errorf("manager IP not configured")
+func (m *Manager) RegisterNode(ctx context.Context) (err error) {
+	if !m.manager.Configured() {
+		return manager.ErrNotConfigured
 	}
+	defer func() { metrics.RecordRegistration(err) }()
 
-	log.Printf("Registering with manager at %s", m.config.ManagerIP)
+	m.log.Info("Registering with manager", "manager", m.manager.URL())
 
 	// Get node information
 	hostname, _ := os.Hostname()
@@ -375,99 +487,178 @@ func (m *Manager) RegisterNode() error {
 	// Get geo location
 	geo, err := m.GetGeoLocation()
 	if err != nil {
-		log.Printf("Warning: Could not get geo location: %v", err)
+		m.log.Warn("Could not get geo location", "error", err)
 		// Continue without geo info
 	}
 
 	// Get bandwidth information (don't force update to avoid delays)
-	bw, err := m.GetBandwidth(false)
+	bw, err := m.GetBandwidth(ctx, false)
 	if err != nil {
-		log.Printf("Warning: Could not get bandwidth info: %v", err)
+		m.log.Warn("Could not get bandwidth info", "error", err)
 		// Continue without bandwidth info
 	}
 
-	// Prepare complete registration data
-	regData := map[string]interface{}{
-		"hostname":  hostname,
-		"resources": resources,
-		"timestamp": time.Now().Unix(),
+	// Ask the manager to keep our previous ID if we had one
+	reg := &manager.Registration{
+		NodeID:    m.NodeID(),
+		Hostname:  hostname,
+		PublicKey: m.PublicKey(),
+		Resources: resources,
+		Timestamp: time.Now().Unix(),
 	}
 
 	// Add optional data if available
 	if geo != nil {
-		regData["geo"] = geo
+		reg.Geo = geo
 	}
 	if bw != nil {
-		regData["bandwidth"] = bw
+		reg.Bandwidth = bw
 	}
 
-	// Marshal to JSON
-	jsonData, err := json.Marshal(regData)
+	result, err := m.manager.Register(ctx, reg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal registration data: %w", err)
+		return err
 	}
 
-	// Send registration to manager
-	url := fmt.Sprintf("http://%s/api/register", m.config.ManagerIP)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	if result.NodeID == "" {
+		m.log.Warn("Manager did not assign a node ID")
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := m.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to connect to manager: %w", err)
+	m.stateMutex.Lock()
+	m.registered = true // Still mark as registered even without nodeID
+	if result.NodeID != "" {
+		m.nodeID = result.NodeID
 	}
-	defer resp.Body.Close()
+	state := &nodeState{NodeID: m.nodeID, RegisteredAt: time.Now().Unix()}
+	m.stateMutex.Unlock()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("registration failed with status: %s", resp.Status)
+	if state.NodeID != "" {
+		if err := saveState(m.config.Load().DataDir, state); err != nil {
+			m.log.Warn("Failed to persist node ID", "error", err)
+		}
 	}
 
-	// Parse response to get node ID
-	var result struct {
-		NodeID string `json:"node_id"`
-		Status string `json:"status"`
+	logging.SetNodeID(state.NodeID)
+	m.log.Info("Registered with manager")
+	return nil
+}
+
+// IsRegistered returns whether the node has registered with a manager
+func (m *Manager) IsRegistered() bool {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	return m.registered
+}
+
+// setRegistered updates the registration status flag
+func (m *Manager) setRegistered(registered bool) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+	m.registered = registered
+}
+
+// NodeID returns the ID assigned by the manager, empty if none yet
+func (m *Manager) NodeID() string {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	return m.nodeID
+}
+
+// PublicKey returns the encoded public half of the node's signing key
+func (m *Manager) PublicKey() string {
+	return signing.EncodePublicKey(m.nodeKey.Public().(ed25519.PublicKey))
+}
+
+// ManagerClient returns the client for the manager API, which signs every
+// request with the node key
+func (m *Manager) ManagerClient() *manager.Client {
+	return m.manager
+}
+
+// NodeInfo returns this node's identity and registration status
+func (m *Manager) NodeInfo() NodeInfo {
+	hostname, _ := os.Hostname()
+
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+	return NodeInfo{
+		NodeID:     m.nodeID,
+		PublicKey:  m.PublicKey(),
+		Registered: m.registered,
+		Hostname:   hostname,
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return fmt.Errorf("failed to parse registration response: %w", err)
+// DeregisterNode tells the manager this node is leaving so it is removed
+// from the registry instead of lingering until heartbeats time out
+func (m *Manager) DeregisterNode(ctx context.Context) error {
+	if !m.manager.Configured() || !m.IsRegistered() {
+		return nil
 	}
 
-	m.registered = true // Still mark as registered even without nodeID
-	log.Printf("Registered successfully")
+	hostname, _ := os.Hostname()
+	err := m.manager.Deregister(ctx, &manager.Deregistration{
+		NodeID:    m.NodeID(),
+		Hostname:  hostname,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	m.setRegistered(false)
+	m.log.Info("Deregistered from manager")
 	return nil
 }
 
-// IsRegistered returns whether the node has registered with a manager
-func (m *Manager) IsRegistered() bool {
-	return m.registered
+// StartBackgroundUpdater schedules the periodic geolocation lookups and
+// bandwidth tests until ctx is done. Each runs in the background with
+// jitter; callers are served the last known values meanwhile.
+func (m *Manager) StartBackgroundUpdater(ctx context.Context) {
+	if m.geoProvider != nil {
+		m.startProbe(ctx, scheduledProbe{
+			name:     "geo location lookup",
+			interval: func(cfg *config.Config) time.Duration { return cfg.GeoInterval },
+			run: func(ctx context.Context) error {
+				_, err := m.refreshGeoLocation()
+				return err
+			},
+		})
+	}
+
+	m.startProbe(ctx, scheduledProbe{
+		name:     "peer bandwidth test",
+		interval: func(cfg *config.Config) time.Duration { return cfg.BandwidthPeerInterval },
+		idleOnly: true,
+		run: func(ctx context.Context) error {
+			_, err := m.MeasurePeers(ctx)
+			return err
+		},
+	})
+
+	m.startProbe(ctx, scheduledProbe{
+		name:     "internet speed test",
+		interval: func(cfg *config.Config) time.Duration { return cfg.BandwidthInterval },
+		idleOnly: true,
+		run: func(ctx context.Context) error {
+			_, err := m.GetBandwidth(ctx, true)
+			return err
+		},
+	})
 }
 
-// StartBackgroundUpdater starts periodic updates of resource-related data
-func (m *Manager) StartBackgroundUpdater() {
-	// Periodically update geo location every 4 hours, retry after 1m if error
-	go func() {
-		for {
-			if _, err := m.GetGeoLocation(); err != nil {
-				fmt.Printf("Error fetching geo location: %v\n", err)
-				time.Sleep(time.Minute)
-				continue
-			}
-			time.Sleep(4 * time.Hour)
-		}
-	}()
+// sleepUntil waits until t. It returns early with due unset when changed
+// is closed, and with ok unset when ctx is done.
+func sleepUntil(ctx context.Context, t time.Time, changed <-chan struct{}) (due, ok bool) {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
 
-	// Periodically update bandwidth every 24 hours, retry after 1m if error
-	go func() {
-		for {
-			if _, err := m.GetBandwidth(true); err != nil {
-				fmt.Printf("Error fetching bandwidth: %v\n", err)
-				time.Sleep(time.Minute)
-				continue
-			}
-			time.Sleep(24 * time.Hour)
-		}
-	}()
+	select {
+	case <-ctx.Done():
+		return false, false
+	case <-changed:
+		return false, true
+	case <-timer.C:
+		return true, true
+	}
 }
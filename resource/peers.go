@@ -0,0 +1,138 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/bandwidth"
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/tlsutil"
+	"github.com/unicornultrafoundation/subnet-rayai-node/manager"
+)
+
+// peerTestTimeout bounds a whole measurement against one peer
+const peerTestTimeout = 2 * time.Minute
+
+// newPeerClient returns the bandwidth client used against other nodes, with
+// the peer TLS settings. The manager's are not reused: its client
+// certificate must not be handed to peers, nor its CA trusted for them.
+func newPeerClient(cfg *config.Config) (*bandwidth.Client, error) {
+	clientTLS, err := tlsutil.NewClientTLS(cfg.PeerCAFile, cfg.PeerClientCertFile, cfg.PeerClientKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer TLS config: %w", err)
+	}
+	return &bandwidth.Client{
+		HTTP: &http.Client{
+			Timeout:   peerTestTimeout,
+			Transport: clientTLS.Transport(),
+		},
+		PayloadSize: cfg.BandwidthPayloadSize,
+	}, nil
+}
+
+// PeerBandwidth returns the results of the last measurement round
+func (m *Manager) PeerBandwidth() []bandwidth.Result {
+	m.peerMutex.RLock()
+	defer m.peerMutex.RUnlock()
+
+	results := make([]bandwidth.Result, len(m.peerResults))
+	copy(results, m.peerResults)
+	return results
+}
+
+// MeasurePeers measures throughput and latency to the head node, the peers
+// assigned by the manager and the configured BANDWIDTH_PEERS, then reports
+// the results to the manager. A call made while a round is in progress
+// waits for that round instead of starting another.
+func (m *Manager) MeasurePeers(ctx context.Context) ([]bandwidth.Result, error) {
+	err := m.peerFlight.Do(func() error {
+		peers := m.peerTargets(ctx)
+		if len(peers) == 0 {
+			return nil
+		}
+
+		results := m.peerClient.MeasureAll(ctx, peers)
+
+		m.peerMutex.Lock()
+		m.peerResults = results
+		m.peerMutex.Unlock()
+
+		if m.config.Load().ManagerIP != "" {
+			return m.reportPeerBandwidth(ctx, results)
+		}
+		return nil
+	})
+	return m.PeerBandwidth(), err
+}
+
+// peerTargets returns the base URLs of the peers to measure, without
+// duplicates
+func (m *Manager) peerTargets(ctx context.Context) []string {
+	var hosts []string
+
+	m.stateMutex.RLock()
+	runtimeStateFn := m.runtimeStateFn
+	m.stateMutex.RUnlock()
+	if runtimeStateFn != nil {
+		if state := runtimeStateFn(); state.Role == "worker" && state.HeadIP != "" {
+			hosts = append(hosts, state.HeadIP)
+		}
+	}
+
+	if m.config.Load().ManagerIP != "" {
+		assigned, err := m.manager.Peers(ctx, m.NodeID())
+		if err != nil {
+			m.log.Warn("Could not fetch peers from manager", "error", err)
+		}
+		hosts = append(hosts, assigned...)
+	}
+
+	hosts = append(hosts, m.config.Load().BandwidthPeers...)
+
+	seen := make(map[string]bool)
+	var targets []string
+	for _, host := range hosts {
+		target := m.peerURL(host)
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// reportPeerBandwidth sends the measurement results to the manager
+func (m *Manager) reportPeerBandwidth(ctx context.Context, results []bandwidth.Result) error {
+	return m.manager.ReportPeerBandwidth(ctx, &manager.PeerReport{
+		NodeID:    m.NodeID(),
+		Timestamp: time.Now().Unix(),
+		Results:   results,
+	})
+}
+
+// peerURL turns a peer given as host, host:port or URL into the base URL of
+// its node API. Peers without a port are assumed to listen on API_PORT and
+// to use TLS when this node does.
+func (m *Manager) peerURL(peer string) string {
+	peer = strings.TrimSpace(peer)
+	if peer == "" {
+		return ""
+	}
+	if strings.Contains(peer, "://") {
+		return strings.TrimSuffix(peer, "/")
+	}
+
+	scheme := "http"
+	if m.config.Load().TLSCertFile != "" {
+		scheme = "https"
+	}
+	if _, _, err := net.SplitHostPort(peer); err != nil {
+		peer = net.JoinHostPort(strings.Trim(peer, "[]"), m.config.Load().APIPort)
+	}
+	return scheme + "://" + peer
+}
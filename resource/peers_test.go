@@ -0,0 +1,102 @@
+package resource
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+func TestPeerClientTLS(t *testing.T) {
+	var presented atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented.Store(int32(len(r.TLS.PeerCertificates)))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The test server's self-signed certificate serves as CA, client
+	// certificate and peer certificate alike
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	key, err := x509.MarshalPKCS8PrivateKey(srv.TLS.Certificates[0].PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, certFile, "CERTIFICATE", srv.Certificate().Raw)
+	writePEM(t, keyFile, "PRIVATE KEY", key)
+
+	tests := []struct {
+		name          string
+		cfg           config.Config
+		wantErr       bool
+		wantPresented int32
+	}{
+		{
+			name: "manager certificate kept from peers",
+			cfg: config.Config{
+				ManagerCAFile: certFile, ManagerClientCertFile: certFile, ManagerClientKeyFile: keyFile,
+				PeerCAFile: certFile,
+			},
+		},
+		{
+			name:          "peer certificate presented",
+			cfg:           config.Config{PeerCAFile: certFile, PeerClientCertFile: certFile, PeerClientKeyFile: keyFile},
+			wantPresented: 1,
+		},
+		{
+			name:    "manager CA not trusted for peers",
+			cfg:     config.Config{ManagerCAFile: certFile},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presented.Store(-1)
+			client, err := newPeerClient(&tt.cfg)
+			if err != nil {
+				t.Fatalf("newPeerClient: %v", err)
+			}
+
+			resp, err := client.HTTP.Get(srv.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("request to an untrusted peer succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			resp.Body.Close()
+			if got := presented.Load(); got != tt.wantPresented {
+				t.Errorf("peer received %d client certificates, want %d", got, tt.wantPresented)
+			}
+		})
+	}
+}
+
+func TestPeerClientInvalidTLS(t *testing.T) {
+	cfg := &config.Config{PeerCAFile: filepath.Join(t.TempDir(), "missing.pem")}
+	if _, err := newPeerClient(cfg); err == nil {
+		t.Error("newPeerClient accepted a missing CA file")
+	}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,146 @@
+package resource
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+)
+
+const (
+	// probeRetryDelay is the wait before retrying a failed probe
+	probeRetryDelay = time.Minute
+	// probeBusyDelay is the wait before checking again whether Ray is idle
+	probeBusyDelay = 5 * time.Minute
+)
+
+// probeStartupSpread bounds the random delay before a probe's first run, so
+// nodes started together don't all measure at once. A variable so tests can
+// shorten it.
+var probeStartupSpread = 30 * time.Second
+
+// scheduledProbe is an expensive measurement repeated in the background.
+// Callers are served its last result in the meantime.
+type scheduledProbe struct {
+	name     string
+	interval func(cfg *config.Config) time.Duration // Zero disables the probe
+	idleOnly bool                                   // Deferred while Ray is running work, if ProbeWhenIdle is set
+	run      func(ctx context.Context) error
+}
+
+// startProbe runs p every interval, jittered, until ctx is done. Failed
+// runs are retried after probeRetryDelay. When the interval is reloaded the
+// next run is rescheduled from the last one.
+func (m *Manager) startProbe(ctx context.Context, p scheduledProbe) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		next := time.Now().Add(time.Duration(rand.Int63n(int64(probeStartupSpread))))
+		var lastRun time.Time
+		planned := p.interval(m.config.Load())
+		for {
+			changed := m.config.Changed()
+			cfg := m.config.Load()
+
+			interval := p.interval(cfg)
+			if interval != planned {
+				planned = interval
+				if !lastRun.IsZero() {
+					next = lastRun.Add(m.jitter(interval))
+				}
+			}
+			if interval <= 0 {
+				// Disabled until a reload enables it
+				select {
+				case <-ctx.Done():
+					return
+				case <-changed:
+					continue
+				}
+			}
+
+			due, ok := sleepUntil(ctx, next, changed)
+			if !ok {
+				return
+			}
+			if !due {
+				continue
+			}
+
+			if p.idleOnly && cfg.ProbeWhenIdle && m.rayBusy() {
+				m.log.Debug("Deferring probe while Ray is busy", "probe", p.name)
+				next = time.Now().Add(m.jitter(probeBusyDelay))
+				continue
+			}
+
+			lastRun = time.Now()
+			next = lastRun.Add(m.jitter(interval))
+			if err := p.run(ctx); err != nil {
+				m.log.Warn("Probe failed", "probe", p.name, "error", err)
+				next = time.Now().Add(m.jitter(probeRetryDelay))
+			}
+		}
+	}()
+}
+
+// jitter spreads d randomly by up to ProbeJitter in either direction
+func (m *Manager) jitter(d time.Duration) time.Duration {
+	spread := int64(float64(d) * m.config.Load().ProbeJitter)
+	if spread <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int63n(2*spread+1)-spread)
+}
+
+// SetBusyFunc registers the callback reporting whether Ray is running work,
+// consulted before bandwidth tests
+func (m *Manager) SetBusyFunc(fn func() bool) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+	m.busyFn = fn
+}
+
+// rayBusy reports whether Ray is running work, false if unknown
+func (m *Manager) rayBusy() bool {
+	m.stateMutex.RLock()
+	busyFn := m.busyFn
+	m.stateMutex.RUnlock()
+	return busyFn != nil && busyFn()
+}
+
+// flight lets concurrent callers share a single execution of a function
+type flight struct {
+	mu   sync.Mutex
+	call *flightCall
+}
+
+// flightCall is an execution in progress
+type flightCall struct {
+	done chan struct{}
+	err  error
+}
+
+// Do runs fn, or if a run is already in progress waits for it and returns
+// its error instead
+func (f *flight) Do(fn func() error) error {
+	f.mu.Lock()
+	if call := f.call; call != nil {
+		f.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	call := &flightCall{done: make(chan struct{})}
+	f.call = call
+	f.mu.Unlock()
+
+	call.err = fn()
+
+	f.mu.Lock()
+	f.call = nil
+	f.mu.Unlock()
+	close(call.done)
+	return call.err
+}
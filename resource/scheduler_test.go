@@ -0,0 +1,189 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/unicornultrafoundation/subnet-rayai-node/config"
+	"github.com/unicornultrafoundation/subnet-rayai-node/internal/logging"
+)
+
+// newSchedulerManager returns a Manager with just enough set up to run
+// probes
+func newSchedulerManager(cfg *config.Config) *Manager {
+	return &Manager{config: config.NewStore(cfg), log: logging.For("resource")}
+}
+
+// waitInFlight waits until f has a run in progress
+func waitInFlight(t *testing.T, f *flight) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		f.mu.Lock()
+		running := f.call != nil
+		f.mu.Unlock()
+		if running {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no run in flight")
+}
+
+func TestFlightCoalescesConcurrentCalls(t *testing.T) {
+	var f flight
+	var runs int32
+	release := make(chan struct{})
+	errShared := errors.New("shared")
+
+	errs := make(chan error, 6)
+	go func() {
+		errs <- f.Do(func() error {
+			atomic.AddInt32(&runs, 1)
+			<-release
+			return errShared
+		})
+	}()
+	waitInFlight(t, &f)
+
+	var arrived sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		arrived.Add(1)
+		go func() {
+			arrived.Done()
+			errs <- f.Do(func() error {
+				atomic.AddInt32(&runs, 1)
+				return errors.New("ran again")
+			})
+		}()
+	}
+	arrived.Wait()
+	time.Sleep(20 * time.Millisecond) // Let the followers reach Do
+	close(release)
+
+	for i := 0; i < 6; i++ {
+		if err := <-errs; err != errShared {
+			t.Errorf("Do = %v, want the shared error", err)
+		}
+	}
+	if got := atomic.LoadInt32(&runs); got != 1 {
+		t.Errorf("fn ran %d times, want 1", got)
+	}
+
+	// Once finished, the next call runs again
+	if err := f.Do(func() error { return nil }); err != nil {
+		t.Errorf("Do after the run = %v, want nil", err)
+	}
+}
+
+func TestForcedBandwidthWaitsForRunningTest(t *testing.T) {
+	m := newSchedulerManager(&config.Config{})
+	measured := &Bandwidth{DownloadMbps: 940, UploadMbps: 420, PingMs: 3}
+	release := make(chan struct{})
+
+	go m.bwFlight.Do(func() error {
+		<-release
+		m.bwMutex.Lock()
+		m.bwCache = measured
+		m.bwMutex.Unlock()
+		return nil
+	})
+	waitInFlight(t, &m.bwFlight)
+
+	if _, err := m.GetBandwidth(context.Background(), false); err != errNotMeasured {
+		t.Errorf("unforced GetBandwidth during the test = %v, want errNotMeasured", err)
+	}
+
+	type result struct {
+		bw  *Bandwidth
+		err error
+	}
+	results := make(chan result, 1)
+	go func() {
+		bw, err := m.GetBandwidth(context.Background(), true)
+		results <- result{bw, err}
+	}()
+
+	select {
+	case r := <-results:
+		t.Fatalf("forced GetBandwidth returned %+v before the running test finished", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case r := <-results:
+		if r.err != nil || r.bw != measured {
+			t.Errorf("forced GetBandwidth = %+v, %v, want the running test's result", r.bw, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("forced GetBandwidth did not return after the running test finished")
+	}
+}
+
+func TestProbeStopsOnCancel(t *testing.T) {
+	defer func(spread time.Duration) { probeStartupSpread = spread }(probeStartupSpread)
+	probeStartupSpread = time.Millisecond
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		blocking bool // The run waits for ctx
+	}{
+		{name: "while running", interval: time.Hour, blocking: true},
+		{name: "while waiting", interval: time.Hour},
+		{name: "while disabled", interval: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newSchedulerManager(&config.Config{})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ran := make(chan struct{}, 1)
+			var runErr error
+			m.startProbe(ctx, scheduledProbe{
+				name:     "test",
+				interval: func(*config.Config) time.Duration { return tt.interval },
+				run: func(ctx context.Context) error {
+					ran <- struct{}{}
+					if tt.blocking {
+						<-ctx.Done()
+						runErr = ctx.Err()
+					}
+					return runErr
+				},
+			})
+
+			if tt.interval > 0 {
+				select {
+				case <-ran:
+				case <-time.After(time.Second):
+					t.Fatal("probe never ran")
+				}
+			}
+			cancel()
+
+			stopped := make(chan struct{})
+			go func() {
+				m.Wait()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(time.Second):
+				t.Fatal("probe loop did not stop after cancel")
+			}
+			if tt.blocking && runErr != context.Canceled {
+				t.Errorf("run saw %v, want context.Canceled", runErr)
+			}
+			if len(ran) != 0 {
+				t.Error("probe ran again after cancel")
+			}
+		})
+	}
+}
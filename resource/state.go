@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// stateFileName is the file under the data directory holding the node state
+const stateFileName = "node.json"
+
+// nodeState is persisted under the data directory so the node keeps its
+// identity across restarts
+type nodeState struct {
+	NodeID       string `json:"node_id"`
+	RegisteredAt int64  `json:"registered_at,omitempty"`
+}
+
+// loadState reads the persisted node state, returning an empty state when
+// none has been written yet
+func loadState(dataDir string) (*nodeState, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, stateFileName))
+	if os.IsNotExist(err) {
+		return &nodeState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node state: %w", err)
+	}
+
+	var state nodeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse node state: %w", err)
+	}
+	return &state, nil
+}
+
+// saveState atomically writes the node state to the data directory
+func saveState(dataDir string, state *nodeState) error {
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal node state: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash never leaves a partial file
+	tmp, err := os.CreateTemp(dataDir, stateFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write node state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write node state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write node state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dataDir, stateFileName)); err != nil {
+		return fmt.Errorf("failed to write node state: %w", err)
+	}
+	return nil
+}
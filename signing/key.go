@@ -0,0 +1,91 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LoadOrCreateKey loads the Ed25519 private key stored in PEM (PKCS #8)
+// form at path, generating and saving a new key on first use
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return parseKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read node key: %w", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate node key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode node key: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	// Write to a temp file so a crash never leaves a partial key behind.
+	// CreateTemp makes it 0600.
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write node key: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := pem.Encode(tmp, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write node key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write node key: %w", err)
+	}
+
+	// Link rather than rename so two processes sharing a data dir never
+	// overwrite each other's key; the loser uses the key that won
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return loadKey(path)
+		}
+		return nil, fmt.Errorf("failed to write node key: %w", err)
+	}
+
+	return key, nil
+}
+
+// loadKey reads the key stored at path
+func loadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node key: %w", err)
+	}
+	return parseKey(data)
+}
+
+// parseKey decodes a PEM encoded PKCS #8 Ed25519 private key
+func parseKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("node key is not a PEM encoded private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("node key is not an Ed25519 key")
+	}
+	return key, nil
+}
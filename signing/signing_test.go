@@ -0,0 +1,289 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func signedRequest(t *testing.T, key ed25519.PrivateKey, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/heartbeat?node_id=n1", bytes.NewBufferString(body))
+	if err := SignRequest(req, key); err != nil {
+		t.Fatalf("SignRequest: %v", err)
+	}
+	return req
+}
+
+// resign replaces the signature of req after its headers were changed
+func resign(t *testing.T, req *http.Request, key ed25519.PrivateKey) {
+	t.Helper()
+	message := CanonicalString(req.Method, req.URL.RequestURI(),
+		req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderNonce), req.Header.Get(HeaderBodyHash))
+	req.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(message))))
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	key := newKey(t)
+	req := signedRequest(t, key, `{"cpu":4}`)
+
+	pub, err := PublicKey(req)
+	if err != nil {
+		t.Fatalf("PublicKey: %v", err)
+	}
+	if !pub.Equal(key.Public()) {
+		t.Fatal("PublicKey does not match the signing key")
+	}
+
+	if err := NewVerifier(0).Verify(req, pub); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// The body is restored for the handler
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"cpu":4}` {
+		t.Errorf("body after Verify = %q", body)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := newKey(t)
+	other := newKey(t)
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request)
+		pub    ed25519.PublicKey
+	}{
+		{
+			name: "tampered body",
+			tamper: func(req *http.Request) {
+				req.Body = io.NopCloser(bytes.NewBufferString(`{"cpu":64}`))
+			},
+		},
+		{
+			name: "tampered body with matching hash",
+			tamper: func(req *http.Request) {
+				body := []byte(`{"cpu":64}`)
+				req.Body = io.NopCloser(bytes.NewReader(body))
+				req.Header.Set(HeaderBodyHash, hashBody(body))
+			},
+		},
+		{
+			name: "tampered path",
+			tamper: func(req *http.Request) {
+				req.URL.RawQuery = "node_id=n2"
+			},
+		},
+		{
+			name: "tampered method",
+			tamper: func(req *http.Request) {
+				req.Method = http.MethodPut
+			},
+		},
+		{
+			name: "wrong key",
+			pub:  other.Public().(ed25519.PublicKey),
+		},
+		{
+			name: "missing signature",
+			tamper: func(req *http.Request) {
+				req.Header.Del(HeaderSignature)
+			},
+		},
+		{
+			name: "malformed signature",
+			tamper: func(req *http.Request) {
+				req.Header.Set(HeaderSignature, "%%%")
+			},
+		},
+		{
+			name: "malformed timestamp",
+			tamper: func(req *http.Request) {
+				req.Header.Set(HeaderTimestamp, "yesterday")
+			},
+		},
+		{
+			name: "timestamp too old",
+			tamper: func(req *http.Request) {
+				req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10))
+				resign(t, req, key)
+			},
+		},
+		{
+			name: "timestamp in the future",
+			tamper: func(req *http.Request) {
+				req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10))
+				resign(t, req, key)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, key, `{"cpu":4}`)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			pub := tt.pub
+			if pub == nil {
+				pub = key.Public().(ed25519.PublicKey)
+			}
+			if err := NewVerifier(5*time.Minute).Verify(req, pub); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAcceptsSkewWithinLimit(t *testing.T) {
+	key := newKey(t)
+	req := signedRequest(t, key, "")
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(-4*time.Minute).Unix(), 10))
+	resign(t, req, key)
+
+	if err := NewVerifier(5*time.Minute).Verify(req, key.Public().(ed25519.PublicKey)); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestVerifyRejectsReplayedNonce(t *testing.T) {
+	key := newKey(t)
+	pub := key.Public().(ed25519.PublicKey)
+	verifier := NewVerifier(0)
+
+	req := signedRequest(t, key, `{}`)
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(bytes.NewBufferString(`{}`))
+
+	if err := verifier.Verify(req, pub); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := verifier.Verify(replay, pub); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("replayed Verify error = %v, want ErrInvalidSignature", err)
+	}
+
+	// A fresh signature of the same request is not a replay
+	if err := verifier.Verify(signedRequest(t, key, `{}`), pub); err != nil {
+		t.Errorf("Verify of a new request: %v", err)
+	}
+}
+
+func TestTransportSignsRequests(t *testing.T) {
+	key := newKey(t)
+	verifier := NewVerifier(0)
+
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifyErr = verifier.Verify(r, key.Public().(ed25519.PublicKey))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, key)}
+	resp, err := client.Post(server.URL+"/api/register", "application/json", bytes.NewBufferString(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if verifyErr != nil {
+		t.Errorf("Verify on server: %v", verifyErr)
+	}
+}
+
+func TestDecodePublicKey(t *testing.T) {
+	key := newKey(t)
+	encoded := EncodePublicKey(key.Public().(ed25519.PublicKey))
+	if pub, err := DecodePublicKey(encoded); err != nil || !pub.Equal(key.Public()) {
+		t.Errorf("DecodePublicKey round trip = %v, %v", pub, err)
+	}
+	for _, bad := range []string{"", "not base64!", EncodePublicKey([]byte("short"))} {
+		if _, err := DecodePublicKey(bad); err == nil {
+			t.Errorf("DecodePublicKey(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "node.key")
+
+	key, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("key file mode = %o, want 600", perm)
+	}
+
+	again, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey again: %v", err)
+	}
+	if !key.Equal(again) {
+		t.Error("key changed between loads")
+	}
+
+	// No temp files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("key directory holds %d files, want 1", len(entries))
+	}
+}
+
+func TestLoadOrCreateKeyConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.key")
+
+	keys := make([]ed25519.PrivateKey, 8)
+	var wg sync.WaitGroup
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key, err := LoadOrCreateKey(path)
+			if err != nil {
+				t.Errorf("LoadOrCreateKey: %v", err)
+			}
+			keys[i] = key
+		}(i)
+	}
+	wg.Wait()
+
+	for _, key := range keys[1:] {
+		if !key.Equal(keys[0]) {
+			t.Fatal("concurrent callers got different keys")
+		}
+	}
+}
+
+func TestLoadOrCreateKeyInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.key")
+	if err := os.WriteFile(path, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateKey(path); err == nil {
+		t.Error("LoadOrCreateKey accepted an invalid key file")
+	}
+}
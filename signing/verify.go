@@ -0,0 +1,114 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSkew is the default tolerance between the signer's and the
+// verifier's clocks
+const DefaultMaxSkew = 5 * time.Minute
+
+// ErrInvalidSignature is returned for requests that fail verification
+var ErrInvalidSignature = errors.New("invalid request signature")
+
+// Verifier checks request signatures and rejects replayed nonces
+type Verifier struct {
+	MaxSkew time.Duration
+
+	mu     sync.Mutex
+	nonces map[string]time.Time // Seen nonces and when they can be forgotten
+}
+
+// NewVerifier creates a Verifier accepting timestamps within maxSkew of
+// the local clock. A zero maxSkew selects DefaultMaxSkew.
+func NewVerifier(maxSkew time.Duration) *Verifier {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	return &Verifier{
+		MaxSkew: maxSkew,
+		nonces:  make(map[string]time.Time),
+	}
+}
+
+// PublicKey returns the public key the request claims to be signed with.
+// Managers can use it to learn a node's key at registration; afterwards
+// requests should be verified against the key on record.
+func PublicKey(req *http.Request) (ed25519.PublicKey, error) {
+	value := req.Header.Get(HeaderPublicKey)
+	if value == "" {
+		return nil, fmt.Errorf("%w: missing %s header", ErrInvalidSignature, HeaderPublicKey)
+	}
+	return DecodePublicKey(value)
+}
+
+// Verify checks that req was signed by pub, is recent and has not been
+// seen before. The request body is read and restored.
+func (v *Verifier) Verify(req *http.Request, pub ed25519.PublicKey) error {
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	bodyHash := req.Header.Get(HeaderBodyHash)
+	signature := req.Header.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || bodyHash == "" || signature == "" {
+		return fmt.Errorf("%w: missing signature headers", ErrInvalidSignature)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	signedAt := time.Unix(unix, 0)
+	if skew := time.Since(signedAt); skew > v.MaxSkew || skew < -v.MaxSkew {
+		return fmt.Errorf("%w: timestamp outside allowed skew", ErrInvalidSignature)
+	}
+
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	if hashBody(body) != bodyHash {
+		return fmt.Errorf("%w: body hash mismatch", ErrInvalidSignature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: invalid signature encoding", ErrInvalidSignature)
+	}
+	message := CanonicalString(req.Method, req.URL.RequestURI(), timestamp, nonce, bodyHash)
+	if !ed25519.Verify(pub, []byte(message), sig) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+
+	// Only remember nonces of valid requests so forged ones cannot fill the cache
+	if !v.rememberNonce(nonce, signedAt) {
+		return fmt.Errorf("%w: replayed nonce", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// rememberNonce records a nonce, returning false if it was already seen.
+// Nonces are kept until their timestamp falls out of the allowed skew.
+func (v *Verifier) rememberNonce(nonce string, signedAt time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	for n, expiry := range v.nonces {
+		if now.After(expiry) {
+			delete(v.nonces, n)
+		}
+	}
+
+	if _, seen := v.nonces[nonce]; seen {
+		return false
+	}
+	v.nonces[nonce] = signedAt.Add(v.MaxSkew)
+	return true
+}
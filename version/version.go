@@ -0,0 +1,6 @@
+// Package version holds the build version of the node agent
+package version
+
+// Version is the agent version, set at build time with
+// -ldflags "-X github.com/unicornultrafoundation/subnet-rayai-node/version.Version=v1.2.3"
+var Version = "dev"